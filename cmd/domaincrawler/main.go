@@ -9,12 +9,19 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/httprate"
 	"github.com/jponc/domain-crawler/api/openapi"
+	adminhandlers "github.com/jponc/domain-crawler/internal/admin/handlers"
+	adminservices "github.com/jponc/domain-crawler/internal/admin/services"
 	"github.com/jponc/domain-crawler/internal/cache"
 	"github.com/jponc/domain-crawler/internal/config"
 	"github.com/jponc/domain-crawler/internal/crawl/handlers"
 	"github.com/jponc/domain-crawler/internal/crawl/services"
 	"github.com/jponc/domain-crawler/internal/extractor"
+	"github.com/jponc/domain-crawler/internal/httpclient"
+	"github.com/jponc/domain-crawler/internal/linkchecker"
+	"github.com/jponc/domain-crawler/internal/metrics"
 	"github.com/jponc/domain-crawler/internal/middlewares"
+	"github.com/jponc/domain-crawler/internal/robots"
+	"github.com/jponc/domain-crawler/internal/urlpolicy"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -51,20 +58,95 @@ func main() {
 
 	// Setup chi router and middlewares
 	r := chi.NewRouter()
-	r.Use(oapiValidatorMiddleware)
-	r.Use(httprate.LimitByIP(config.RateLimitRPM, time.Minute))
+
+	// Metrics are scraped by Prometheus, they are neither part of the API spec nor rate limited
+	r.Handle("/metrics", metrics.Handler())
+
+	api := r.With(oapiValidatorMiddleware, httprate.LimitByIP(config.RateLimitRPM, time.Minute))
 
 	// Setup dependencies
-	httpClient := &http.Client{}
-	inmemoryCache := cache.NewInMemoryCache()
-	extractorClient := extractor.NewExtractorClient(httpClient, inmemoryCache)
-	crawlService := services.NewCrawlService(extractorClient, config.ExtractorConcurrentLimit)
+	urlPolicy, err := urlpolicy.New(urlpolicy.Options{
+		AllowedHosts: config.URLAllowedHosts,
+		DeniedHosts:  config.URLDeniedHosts,
+		AllowedCIDRs: config.URLAllowedCIDRs,
+		DeniedCIDRs:  config.URLDeniedCIDRs,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to setup url policy")
+	}
+
+	httpClient, err := httpclient.New(httpclient.Options{
+		ConnectTimeout:        config.HTTPConnectTimeout,
+		TLSHandshakeTimeout:   config.HTTPTLSHandshakeTimeout,
+		ResponseHeaderTimeout: config.HTTPResponseHeaderTimeout,
+		Timeout:               config.HTTPTimeout,
+		UserAgent:             config.HTTPUserAgent,
+		Headers:               config.HTTPHeaders,
+		ProxyURL:              config.HTTPProxyURL,
+		MaxIdleConns:          config.HTTPMaxIdleConns,
+		MaxIdleConnsPerHost:   config.HTTPMaxIdleConnsPerHost,
+		IdleConnTimeout:       config.HTTPIdleConnTimeout,
+		URLPolicy:             urlPolicy,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to setup http client")
+	}
+
+	htmlCache, err := cache.New(cache.Options{
+		Backend:       config.CacheBackend,
+		TTL:           config.CacheTTL,
+		MaxBytes:      config.CacheMaxBytes,
+		Dir:           config.CacheDir,
+		RedisAddr:     config.CacheRedisAddr,
+		RedisPassword: config.CacheRedisPassword,
+		RedisDB:       config.CacheRedisDB,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to setup cache")
+	}
+
+	retryPolicy := extractor.RetryPolicy{
+		MaxAttempts: config.RetryMaxAttempts,
+		BaseDelay:   config.RetryBaseDelay,
+		MaxDelay:    config.RetryMaxDelay,
+	}
+	breaker := extractor.NewCircuitBreaker(extractor.BreakerPolicy{
+		FailureThreshold: config.BreakerFailureThreshold,
+		OpenDuration:     config.BreakerOpenDuration,
+	})
+	extractorClient := extractor.NewExtractorClient(httpClient, htmlCache, retryPolicy, breaker, config.ExtractorMaxBodyBytes)
+	linkChecker := linkchecker.NewLinkChecker(httpClient, config.LinkCheckConcurrentLimit, config.LinkCheckTimeout)
+	robotsChecker := robots.NewRobotsChecker(httpClient, config.RobotsUserAgent, config.RobotsCacheTTL)
+	hostScheduler := services.NewHostScheduler(config.HostConcurrentLimit, config.HostRequestDelay)
+	crawlService := services.NewCrawlService(extractorClient, linkChecker, robotsChecker, hostScheduler, urlPolicy, config.ExtractorConcurrentLimit)
+	jobService := services.NewJobService(crawlService, config.JobsConcurrentLimit, config.JobsRetention)
+	cacheService := adminservices.NewCacheService(htmlCache)
+	breakerService := adminservices.NewBreakerService(breaker)
 
 	// Setup handlers
 	crawlHandler := handlers.NewCrawlHandler(crawlService)
+	jobHandler := handlers.NewJobHandler(jobService)
+	cacheHandler := adminhandlers.NewCacheHandler(cacheService)
+	breakerHandler := adminhandlers.NewBreakerHandler(breakerService)
 
 	// Setup routes
-	r.Post("/crawl", crawlHandler.Crawl)
+	api.Post("/crawl", crawlHandler.Crawl)
+	api.Post("/jobs", jobHandler.CreateJob)
+	api.Get("/jobs/{id}", jobHandler.GetJob)
+	api.Delete("/jobs/{id}", jobHandler.CancelJob)
+	api.Get("/jobs/{id}/results", jobHandler.GetJobResults)
+
+	if config.AdminToken == "" {
+		log.Warn().Msg("ADMIN_TOKEN isn't set, admin endpoints reject every request")
+	}
+
+	admin := api.With(middlewares.AdminAuth(config.AdminToken))
+	admin.Get("/cache", cacheHandler.ListEntries)
+	admin.Delete("/cache", cacheHandler.PurgeEntries)
+	admin.Get("/cache/stats", cacheHandler.GetStats)
+	admin.Get("/cache/{url}", cacheHandler.GetEntry)
+	admin.Delete("/cache/{url}", cacheHandler.DeleteEntry)
+	admin.Get("/breakers", breakerHandler.ListBreakers)
 
 	// Start server
 	addr := fmt.Sprintf(":%s", config.Port)
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jponc/domain-crawler/internal/extractor"
+)
+
+type breakerService interface {
+	List(state extractor.BreakerState) []extractor.HostBreaker
+}
+
+type breakerHandler struct {
+	breakerService breakerService
+}
+
+func NewBreakerHandler(breakerService breakerService) *breakerHandler {
+	h := &breakerHandler{
+		breakerService: breakerService,
+	}
+
+	return h
+}
+
+func (h *breakerHandler) ListBreakers(w http.ResponseWriter, r *http.Request) {
+	hosts := h.breakerService.List(extractor.BreakerState(r.URL.Query().Get("state")))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(convertHostBreakersToBreakersResponse(hosts))
+}
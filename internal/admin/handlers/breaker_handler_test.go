@@ -0,0 +1,148 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/jponc/domain-crawler/api/openapi"
+	"github.com/jponc/domain-crawler/internal/admin/handlers"
+	"github.com/jponc/domain-crawler/internal/extractor"
+	"github.com/jponc/domain-crawler/internal/middlewares"
+	"github.com/kinbiko/jsonassert"
+	"github.com/stretchr/testify/require"
+)
+
+// Mocks
+type mockBreakerService struct {
+	listFn func(state extractor.BreakerState) []extractor.HostBreaker
+}
+
+func (m *mockBreakerService) List(state extractor.BreakerState) []extractor.HostBreaker {
+	if m != nil && m.listFn != nil {
+		return m.listFn(state)
+	}
+
+	return []extractor.HostBreaker{}
+}
+
+func TestBreakerHandler(t *testing.T) {
+	openedAt := time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                 string
+		path                 string
+		token                string
+		mockBreakerService   *mockBreakerService
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:               "returns 401 without the admin token",
+			path:               "/breakers",
+			mockBreakerService: &mockBreakerService{},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponseBody: `
+				{
+					"error": "unauthorized"
+				}`,
+		},
+		{
+			name:  "returns 200 with the circuit of failing hosts",
+			path:  "/breakers",
+			token: adminToken,
+			mockBreakerService: &mockBreakerService{
+				listFn: func(state extractor.BreakerState) []extractor.HostBreaker {
+					require.Empty(t, state)
+
+					return []extractor.HostBreaker{
+						{Host: "down.com", State: extractor.BreakerOpen, ConsecutiveFailures: 5, OpenedAt: openedAt, RetryAt: openedAt.Add(30 * time.Second)},
+						{Host: "flaky.com", State: extractor.BreakerClosed, ConsecutiveFailures: 1},
+					}
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"hosts": [
+						{
+							"host": "down.com",
+							"state": "open",
+							"consecutive_failures": 5,
+							"opened_at": "2024-09-01T10:00:00Z",
+							"retry_at": "2024-09-01T10:00:30Z"
+						},
+						{
+							"host": "flaky.com",
+							"state": "closed",
+							"consecutive_failures": 1
+						}
+					]
+				}`,
+		},
+		{
+			name:  "passes the state filter to breaker service",
+			path:  "/breakers?state=half_open",
+			token: adminToken,
+			mockBreakerService: &mockBreakerService{
+				listFn: func(state extractor.BreakerState) []extractor.HostBreaker {
+					require.Equal(t, extractor.BreakerHalfOpen, state)
+					return []extractor.HostBreaker{}
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"hosts": []
+				}`,
+		},
+		{
+			name:               "returns 400 when state is unknown",
+			path:               "/breakers?state=broken",
+			token:              adminToken,
+			mockBreakerService: &mockBreakerService{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// initialise router with openapi spec
+			openapiSpec, err := openapi.FS.ReadFile(openapi.OpenAPISpecFilename)
+			require.NoError(t, err)
+
+			loader := openapi3.NewLoader()
+			doc, err := loader.LoadFromData(openapiSpec)
+			require.NoError(t, err)
+
+			oapiValidatorMiddleware := middlewares.OpenAPIValidatorMiddleware(doc)
+			router := chi.NewRouter()
+			router.Use(oapiValidatorMiddleware)
+			router.Use(middlewares.AdminAuth(adminToken))
+
+			// initialise handlers
+			h := handlers.NewBreakerHandler(tt.mockBreakerService)
+
+			// setup routes
+			router.Get("/breakers", h.ListBreakers)
+
+			// create request
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			require.Equal(t, tt.expectedStatusCode, w.Code)
+			if tt.expectedResponseBody != "" {
+				jsonassert.New(t).Assertf(w.Body.String(), "%s", tt.expectedResponseBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jponc/domain-crawler/internal/admin/services"
+	"github.com/jponc/domain-crawler/internal/cache"
+	"github.com/jponc/domain-crawler/internal/errs"
+)
+
+type cacheService interface {
+	List(filter services.CacheFilter) []cache.Entry
+	Get(key string) (cache.Entry, error)
+	Delete(key string) error
+	Purge(filter services.CacheFilter) int
+	Stats() services.CacheStats
+}
+
+type cacheHandler struct {
+	cacheService cacheService
+}
+
+func NewCacheHandler(cacheService cacheService) *cacheHandler {
+	h := &cacheHandler{
+		cacheService: cacheService,
+	}
+
+	return h
+}
+
+func (h *cacheHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	entries := h.cacheService.List(cacheFilterFromQuery(r))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(convertCacheEntriesToCacheEntriesResponse(entries, time.Now()))
+}
+
+func (h *cacheHandler) GetEntry(w http.ResponseWriter, r *http.Request) {
+	key, ok := cacheKeyFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	e, err := h.cacheService.Get(key)
+	if err != nil {
+		writeCacheError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(convertCacheEntryToCacheEntryResponse(e, time.Now()))
+}
+
+func (h *cacheHandler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
+	key, ok := cacheKeyFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.cacheService.Delete(key)
+	if err != nil {
+		writeCacheError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *cacheHandler) PurgeEntries(w http.ResponseWriter, r *http.Request) {
+	filter := cacheFilterFromQuery(r)
+
+	// Wiping the whole cache by forgetting a parameter is too easy, at least one is required
+	if filter.Prefix == "" && filter.Host == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		errResp := errs.ErrorResponse{Error: "prefix or host is required"}
+		_ = json.NewEncoder(w).Encode(errResp)
+		return
+	}
+
+	purged := h.cacheService.Purge(filter)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(CachePurgeResponse{Purged: purged})
+}
+
+func (h *cacheHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(convertCacheStatsToCacheStatsResponse(h.cacheService.Stats()))
+}
+
+func cacheFilterFromQuery(r *http.Request) services.CacheFilter {
+	return services.CacheFilter{
+		Prefix: r.URL.Query().Get("prefix"),
+		Host:   r.URL.Query().Get("host"),
+	}
+}
+
+// cacheKeyFromPath returns the cached URL, which is percent-encoded in the path so its slashes don't split it
+func cacheKeyFromPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key, err := url.PathUnescape(chi.URLParam(r, "url"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errResp := errs.ErrorResponse{Error: "failed to decode url"}
+		_ = json.NewEncoder(w).Encode(errResp)
+		return "", false
+	}
+
+	return key, true
+}
+
+// writeCacheError maps cache service errors to a response
+func writeCacheError(w http.ResponseWriter, err error) {
+	statusCode := http.StatusInternalServerError
+	if errors.Is(err, services.ErrCacheEntryNotFound) {
+		statusCode = http.StatusNotFound
+	}
+
+	w.WriteHeader(statusCode)
+	errResp := errs.ErrorResponse{Error: err.Error()}
+	_ = json.NewEncoder(w).Encode(errResp)
+}
@@ -0,0 +1,280 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/jponc/domain-crawler/api/openapi"
+	"github.com/jponc/domain-crawler/internal/admin/handlers"
+	"github.com/jponc/domain-crawler/internal/admin/services"
+	"github.com/jponc/domain-crawler/internal/cache"
+	"github.com/jponc/domain-crawler/internal/middlewares"
+	"github.com/kinbiko/jsonassert"
+	"github.com/stretchr/testify/require"
+)
+
+// Mocks
+type mockCacheService struct {
+	listFn   func(filter services.CacheFilter) []cache.Entry
+	getFn    func(key string) (cache.Entry, error)
+	deleteFn func(key string) error
+	purgeFn  func(filter services.CacheFilter) int
+	statsFn  func() services.CacheStats
+}
+
+func (m *mockCacheService) List(filter services.CacheFilter) []cache.Entry {
+	if m != nil && m.listFn != nil {
+		return m.listFn(filter)
+	}
+
+	return []cache.Entry{}
+}
+
+func (m *mockCacheService) Get(key string) (cache.Entry, error) {
+	if m != nil && m.getFn != nil {
+		return m.getFn(key)
+	}
+
+	return cache.Entry{}, services.ErrCacheEntryNotFound
+}
+
+func (m *mockCacheService) Delete(key string) error {
+	if m != nil && m.deleteFn != nil {
+		return m.deleteFn(key)
+	}
+
+	return services.ErrCacheEntryNotFound
+}
+
+func (m *mockCacheService) Purge(filter services.CacheFilter) int {
+	if m != nil && m.purgeFn != nil {
+		return m.purgeFn(filter)
+	}
+
+	return 0
+}
+
+func (m *mockCacheService) Stats() services.CacheStats {
+	if m != nil && m.statsFn != nil {
+		return m.statsFn()
+	}
+
+	return services.CacheStats{}
+}
+
+const adminToken = "secret"
+
+var (
+	entryStoredAt  = time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC)
+	entryExpiresAt = time.Date(2024, 9, 1, 11, 0, 0, 0, time.UTC)
+)
+
+func TestCacheHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		method               string
+		path                 string
+		token                string
+		mockCacheService     *mockCacheService
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:               "returns 401 without the admin token",
+			method:             http.MethodGet,
+			path:               "/cache/stats",
+			mockCacheService:   &mockCacheService{},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponseBody: `
+				{
+					"error": "unauthorized"
+				}`,
+		},
+		{
+			name:               "returns 401 with the wrong admin token",
+			method:             http.MethodGet,
+			path:               "/cache/stats",
+			token:              "wrong",
+			mockCacheService:   &mockCacheService{},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponseBody: `
+				{
+					"error": "unauthorized"
+				}`,
+		},
+		{
+			name:   "returns 200 with the cache stats",
+			method: http.MethodGet,
+			path:   "/cache/stats",
+			token:  adminToken,
+			mockCacheService: &mockCacheService{
+				statsFn: func() services.CacheStats {
+					return services.CacheStats{Hits: 3, Misses: 1, HitRatio: 0.75, Entries: 2, Bytes: 100}
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"hits": 3,
+					"misses": 1,
+					"hit_ratio": 0.75,
+					"entries": 2,
+					"bytes": 100
+				}`,
+		},
+		{
+			name:   "returns 200 with the filtered entries",
+			method: http.MethodGet,
+			path:   "/cache?host=example.com",
+			token:  adminToken,
+			mockCacheService: &mockCacheService{
+				listFn: func(filter services.CacheFilter) []cache.Entry {
+					require.Equal(t, services.CacheFilter{Host: "example.com"}, filter)
+
+					return []cache.Entry{
+						{Key: "https://example.com/", Size: 10, StoredAt: entryStoredAt, ExpiresAt: entryExpiresAt},
+						{Key: "https://example.com/blog", Size: 20},
+					}
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"entries": [
+						{
+							"key": "https://example.com/",
+							"size_bytes": 10,
+							"stored_at": "2024-09-01T10:00:00Z",
+							"age_seconds": "<<PRESENCE>>",
+							"expires_at": "2024-09-01T11:00:00Z"
+						},
+						{
+							"key": "https://example.com/blog",
+							"size_bytes": 20
+						}
+					]
+				}`,
+		},
+		{
+			name:   "returns 200 with the entry of a percent-encoded url",
+			method: http.MethodGet,
+			path:   "/cache/https%3A%2F%2Fexample.com%2Fblog%3Fpage%3D2",
+			token:  adminToken,
+			mockCacheService: &mockCacheService{
+				getFn: func(key string) (cache.Entry, error) {
+					require.Equal(t, "https://example.com/blog?page=2", key)
+
+					return cache.Entry{Key: key, Size: 10}, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"key": "https://example.com/blog?page=2",
+					"size_bytes": 10
+				}`,
+		},
+		{
+			name:               "returns 404 when entry doesn't exist",
+			method:             http.MethodGet,
+			path:               "/cache/https%3A%2F%2Fexample.com%2F",
+			token:              adminToken,
+			mockCacheService:   &mockCacheService{},
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponseBody: `
+				{
+					"error": "cache entry not found"
+				}`,
+		},
+		{
+			name:   "returns 204 when entry is deleted",
+			method: http.MethodDelete,
+			path:   "/cache/https%3A%2F%2Fexample.com%2F",
+			token:  adminToken,
+			mockCacheService: &mockCacheService{
+				deleteFn: func(key string) error {
+					require.Equal(t, "https://example.com/", key)
+
+					return nil
+				},
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:   "returns 200 with the number of purged entries",
+			method: http.MethodDelete,
+			path:   "/cache?prefix=https%3A%2F%2Fexample.com%2Fblog",
+			token:  adminToken,
+			mockCacheService: &mockCacheService{
+				purgeFn: func(filter services.CacheFilter) int {
+					require.Equal(t, services.CacheFilter{Prefix: "https://example.com/blog"}, filter)
+
+					return 2
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"purged": 2
+				}`,
+		},
+		{
+			name:               "returns 400 when purging without a filter",
+			method:             http.MethodDelete,
+			path:               "/cache",
+			token:              adminToken,
+			mockCacheService:   &mockCacheService{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: `
+				{
+					"error": "prefix or host is required"
+				}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// initialise router with openapi spec
+			openapiSpec, err := openapi.FS.ReadFile(openapi.OpenAPISpecFilename)
+			require.NoError(t, err)
+
+			loader := openapi3.NewLoader()
+			doc, err := loader.LoadFromData(openapiSpec)
+			require.NoError(t, err)
+
+			oapiValidatorMiddleware := middlewares.OpenAPIValidatorMiddleware(doc)
+			router := chi.NewRouter()
+			router.Use(oapiValidatorMiddleware)
+			router.Use(middlewares.AdminAuth(adminToken))
+
+			// initialise handlers
+			h := handlers.NewCacheHandler(tt.mockCacheService)
+
+			// setup routes
+			router.Get("/cache", h.ListEntries)
+			router.Delete("/cache", h.PurgeEntries)
+			router.Get("/cache/stats", h.GetStats)
+			router.Get("/cache/{url}", h.GetEntry)
+			router.Delete("/cache/{url}", h.DeleteEntry)
+
+			// create request
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			require.Equal(t, tt.expectedStatusCode, w.Code)
+			if tt.expectedResponseBody != "" {
+				jsonassert.New(t).Assertf(w.Body.String(), "%s", tt.expectedResponseBody)
+			}
+		})
+	}
+}
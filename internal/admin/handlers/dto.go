@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/admin/services"
+	"github.com/jponc/domain-crawler/internal/cache"
+	"github.com/jponc/domain-crawler/internal/extractor"
+)
+
+type CacheEntryResponse struct {
+	Key       string     `json:"key"`
+	SizeBytes int64      `json:"size_bytes"`
+	StoredAt  *time.Time `json:"stored_at,omitempty"`
+	// AgeSeconds is only known when the backend knows when the entry was stored
+	AgeSeconds *int64     `json:"age_seconds,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+type CacheEntriesResponse struct {
+	Entries []CacheEntryResponse `json:"entries"`
+}
+
+type CachePurgeResponse struct {
+	Purged int `json:"purged"`
+}
+
+type CacheStatsResponse struct {
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+	Entries  int     `json:"entries"`
+	Bytes    int64   `json:"bytes"`
+}
+
+func convertCacheEntryToCacheEntryResponse(e cache.Entry, now time.Time) CacheEntryResponse {
+	res := CacheEntryResponse{
+		Key:       e.Key,
+		SizeBytes: e.Size,
+	}
+
+	if !e.StoredAt.IsZero() {
+		storedAt := e.StoredAt
+		age := int64(now.Sub(storedAt).Seconds())
+		res.StoredAt = &storedAt
+		res.AgeSeconds = &age
+	}
+
+	if !e.ExpiresAt.IsZero() {
+		expiresAt := e.ExpiresAt
+		res.ExpiresAt = &expiresAt
+	}
+
+	return res
+}
+
+func convertCacheEntriesToCacheEntriesResponse(entries []cache.Entry, now time.Time) CacheEntriesResponse {
+	res := CacheEntriesResponse{
+		Entries: []CacheEntryResponse{},
+	}
+	for _, e := range entries {
+		res.Entries = append(res.Entries, convertCacheEntryToCacheEntryResponse(e, now))
+	}
+
+	return res
+}
+
+func convertCacheStatsToCacheStatsResponse(s services.CacheStats) CacheStatsResponse {
+	return CacheStatsResponse{
+		Hits:     s.Hits,
+		Misses:   s.Misses,
+		HitRatio: s.HitRatio,
+		Entries:  s.Entries,
+		Bytes:    s.Bytes,
+	}
+}
+
+type BreakerResponse struct {
+	Host                string `json:"host"`
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	// OpenedAt and RetryAt are only set while the circuit isn't closed
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	RetryAt  *time.Time `json:"retry_at,omitempty"`
+}
+
+type BreakersResponse struct {
+	Hosts []BreakerResponse `json:"hosts"`
+}
+
+func convertHostBreakersToBreakersResponse(hosts []extractor.HostBreaker) BreakersResponse {
+	res := BreakersResponse{
+		Hosts: []BreakerResponse{},
+	}
+	for _, h := range hosts {
+		b := BreakerResponse{
+			Host:                h.Host,
+			State:               string(h.State),
+			ConsecutiveFailures: h.ConsecutiveFailures,
+		}
+		if !h.OpenedAt.IsZero() {
+			openedAt, retryAt := h.OpenedAt, h.RetryAt
+			b.OpenedAt = &openedAt
+			b.RetryAt = &retryAt
+		}
+		res.Hosts = append(res.Hosts, b)
+	}
+
+	return res
+}
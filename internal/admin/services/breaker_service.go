@@ -0,0 +1,38 @@
+package services
+
+import (
+	"github.com/jponc/domain-crawler/internal/extractor"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+type circuitBreaker interface {
+	Hosts() []extractor.HostBreaker
+}
+
+type breakerService struct {
+	breaker circuitBreaker
+	logger  zerolog.Logger
+}
+
+// NewBreakerService returns a service to inspect the circuit breaker of the extractor
+func NewBreakerService(breaker circuitBreaker) *breakerService {
+	s := &breakerService{
+		breaker: breaker,
+		logger:  log.With().Str("package", "services").Str("service", "BreakerService").Logger(),
+	}
+
+	return s
+}
+
+// List returns the circuit of the hosts which failed since they last succeeded, only those in state when it is set
+func (s *breakerService) List(state extractor.BreakerState) []extractor.HostBreaker {
+	hosts := []extractor.HostBreaker{}
+	for _, h := range s.breaker.Hosts() {
+		if state == "" || h.State == state {
+			hosts = append(hosts, h)
+		}
+	}
+
+	return hosts
+}
@@ -0,0 +1,56 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/jponc/domain-crawler/internal/admin/services"
+	"github.com/jponc/domain-crawler/internal/extractor"
+	"github.com/stretchr/testify/require"
+)
+
+// Mocks
+type mockCircuitBreaker struct {
+	hosts []extractor.HostBreaker
+}
+
+func (m *mockCircuitBreaker) Hosts() []extractor.HostBreaker {
+	return m.hosts
+}
+
+func TestBreakerService_List(t *testing.T) {
+	breaker := &mockCircuitBreaker{
+		hosts: []extractor.HostBreaker{
+			{Host: "down.com", State: extractor.BreakerOpen, ConsecutiveFailures: 5},
+			{Host: "flaky.com", State: extractor.BreakerClosed, ConsecutiveFailures: 1},
+			{Host: "probed.com", State: extractor.BreakerHalfOpen, ConsecutiveFailures: 5},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		state         extractor.BreakerState
+		expectedHosts []string
+	}{
+		{
+			name:          "returns every host without a state",
+			expectedHosts: []string{"down.com", "flaky.com", "probed.com"},
+		},
+		{
+			name:          "filters by state",
+			state:         extractor.BreakerOpen,
+			expectedHosts: []string{"down.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := services.NewBreakerService(breaker)
+
+			hosts := []string{}
+			for _, h := range s.List(tt.state) {
+				hosts = append(hosts, h.Host)
+			}
+			require.Equal(t, tt.expectedHosts, hosts)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/jponc/domain-crawler/internal/cache"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrCacheEntryNotFound = errors.New("cache entry not found")
+
+type cacheBackend interface {
+	Entries() []cache.Entry
+	Delete(k string) bool
+	Stats() cache.Stats
+}
+
+type cacheService struct {
+	backend cacheBackend
+	logger  zerolog.Logger
+}
+
+// NewCacheService returns a service to inspect and purge the HTML cache, whichever backend stores it
+func NewCacheService(backend cacheBackend) *cacheService {
+	s := &cacheService{
+		backend: backend,
+		logger:  log.With().Str("package", "services").Str("service", "CacheService").Logger(),
+	}
+
+	return s
+}
+
+// List returns the entries selected by filter
+func (s *cacheService) List(filter CacheFilter) []cache.Entry {
+	entries := []cache.Entry{}
+	for _, e := range s.backend.Entries() {
+		if filter.matches(e.Key) {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries
+}
+
+func (s *cacheService) Get(key string) (cache.Entry, error) {
+	for _, e := range s.backend.Entries() {
+		if e.Key == key {
+			return e, nil
+		}
+	}
+
+	return cache.Entry{}, ErrCacheEntryNotFound
+}
+
+func (s *cacheService) Delete(key string) error {
+	if !s.backend.Delete(key) {
+		return ErrCacheEntryNotFound
+	}
+
+	s.logger.Info().Str("key", key).Msg("Deleted cache entry")
+	return nil
+}
+
+// Purge deletes the entries selected by filter and returns how many were deleted
+func (s *cacheService) Purge(filter CacheFilter) int {
+	purged := 0
+	for _, e := range s.List(filter) {
+		if s.backend.Delete(e.Key) {
+			purged++
+		}
+	}
+
+	s.logger.Info().Str("prefix", filter.Prefix).Str("host", filter.Host).Int("purged", purged).Msg("Purged cache entries")
+	return purged
+}
+
+func (s *cacheService) Stats() CacheStats {
+	stats := s.backend.Stats()
+
+	cacheStats := CacheStats{
+		Hits:    stats.Hits,
+		Misses:  stats.Misses,
+		Entries: stats.Entries,
+		Bytes:   stats.Bytes,
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		cacheStats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+
+	return cacheStats
+}
@@ -0,0 +1,132 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/admin/services"
+	"github.com/jponc/domain-crawler/internal/cache"
+	"github.com/stretchr/testify/require"
+)
+
+// Mocks
+type mockCacheBackend struct {
+	entries []cache.Entry
+	hits    int64
+	misses  int64
+}
+
+func (m *mockCacheBackend) Entries() []cache.Entry {
+	return m.entries
+}
+
+func (m *mockCacheBackend) Delete(k string) bool {
+	for i, e := range m.entries {
+		if e.Key == k {
+			m.entries = append(m.entries[:i:i], m.entries[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *mockCacheBackend) Stats() cache.Stats {
+	s := cache.Stats{Hits: m.hits, Misses: m.misses, Entries: len(m.entries)}
+	for _, e := range m.entries {
+		s.Bytes += e.Size
+	}
+
+	return s
+}
+
+func newMockCacheBackend() *mockCacheBackend {
+	storedAt := time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	return &mockCacheBackend{
+		entries: []cache.Entry{
+			{Key: "https://example.com/", Size: 10, StoredAt: storedAt},
+			{Key: "https://example.com/blog", Size: 20, StoredAt: storedAt},
+			{Key: "https://Blog.Example.com/", Size: 30, StoredAt: storedAt},
+			{Key: "https://other.com/", Size: 40, StoredAt: storedAt},
+		},
+		hits:   3,
+		misses: 1,
+	}
+}
+
+func keys(entries []cache.Entry) []string {
+	keys := []string{}
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+
+	return keys
+}
+
+func TestCacheService_List(t *testing.T) {
+	tests := []struct {
+		name         string
+		filter       services.CacheFilter
+		expectedKeys []string
+	}{
+		{
+			name:         "returns every entry without a filter",
+			filter:       services.CacheFilter{},
+			expectedKeys: []string{"https://example.com/", "https://example.com/blog", "https://Blog.Example.com/", "https://other.com/"},
+		},
+		{
+			name:         "filters by prefix",
+			filter:       services.CacheFilter{Prefix: "https://example.com/b"},
+			expectedKeys: []string{"https://example.com/blog"},
+		},
+		{
+			name:         "filters by exact host case insensitively",
+			filter:       services.CacheFilter{Host: "blog.example.com"},
+			expectedKeys: []string{"https://Blog.Example.com/"},
+		},
+		{
+			name:         "combines prefix and host",
+			filter:       services.CacheFilter{Prefix: "https://example.com/blog", Host: "other.com"},
+			expectedKeys: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := services.NewCacheService(newMockCacheBackend())
+			require.Equal(t, tt.expectedKeys, keys(s.List(tt.filter)))
+		})
+	}
+}
+
+func TestCacheService_GetDelete(t *testing.T) {
+	s := services.NewCacheService(newMockCacheBackend())
+
+	e, err := s.Get("https://other.com/")
+	require.NoError(t, err)
+	require.Equal(t, int64(40), e.Size)
+
+	require.NoError(t, s.Delete("https://other.com/"))
+
+	_, err = s.Get("https://other.com/")
+	require.ErrorIs(t, err, services.ErrCacheEntryNotFound)
+	require.ErrorIs(t, s.Delete("https://other.com/"), services.ErrCacheEntryNotFound)
+}
+
+func TestCacheService_Purge(t *testing.T) {
+	backend := newMockCacheBackend()
+	s := services.NewCacheService(backend)
+
+	purged := s.Purge(services.CacheFilter{Host: "example.com"})
+	require.Equal(t, 2, purged)
+	require.Equal(t, []string{"https://Blog.Example.com/", "https://other.com/"}, keys(backend.entries))
+}
+
+func TestCacheService_Stats(t *testing.T) {
+	s := services.NewCacheService(newMockCacheBackend())
+	require.Equal(t, services.CacheStats{Hits: 3, Misses: 1, HitRatio: 0.75, Entries: 4, Bytes: 100}, s.Stats())
+
+	s = services.NewCacheService(&mockCacheBackend{})
+	require.Equal(t, services.CacheStats{}, s.Stats())
+}
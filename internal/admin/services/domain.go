@@ -0,0 +1,38 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+)
+
+// CacheFilter selects cache entries by key prefix and/or host, an empty filter selects every entry
+type CacheFilter struct {
+	// Prefix matches keys, i.e. URLs, starting with it
+	Prefix string
+	// Host matches keys whose URL host is exactly it, case insensitively
+	Host string
+}
+
+func (f CacheFilter) matches(key string) bool {
+	if f.Prefix != "" && !strings.HasPrefix(key, f.Prefix) {
+		return false
+	}
+
+	if f.Host != "" {
+		u, err := url.Parse(key)
+		if err != nil || !strings.EqualFold(u.Hostname(), f.Host) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	// HitRatio is the share of lookups served from cache, zero before the first lookup
+	HitRatio float64
+	Entries  int
+	Bytes    int64
+}
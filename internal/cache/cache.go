@@ -1,23 +1,94 @@
 package cache
 
-// NOTE: This is a very basic cache implementation and is not thread safe.
-// I also didn't introduce any TTL for the cache.
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
 
-type cache struct {
-	cache map[string]string
+const (
+	BackendMemory = "memory"
+	BackendDisk   = "disk"
+	BackendRedis  = "redis"
+)
+
+// Backend is implemented by every cache backend
+type Backend interface {
+	Get(k string) (string, bool)
+	Set(k string, v string)
+	// Delete removes a single entry, it returns false when there was nothing to remove
+	Delete(k string) bool
+	// Entries lists the metadata of every live entry, values are left out
+	Entries() []Entry
+	Stats() Stats
+}
+
+// Entry describes a cached value without its content
+type Entry struct {
+	Key      string
+	Size     int64
+	StoredAt time.Time
+	// ExpiresAt is zero for entries which only leave the cache when evicted
+	ExpiresAt time.Time
+}
+
+// Stats are aggregated over the lifetime of the process, entries and bytes reflect the current content
+type Stats struct {
+	Hits    int64
+	Misses  int64
+	Entries int
+	Bytes   int64
+}
+
+// Options configures the cache backend returned by New
+type Options struct {
+	Backend       string
+	TTL           time.Duration
+	MaxBytes      int64
+	Dir           string
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
 }
 
-func NewInMemoryCache() *cache {
-	return &cache{
-		cache: make(map[string]string),
+// New returns the cache backend selected by opts.Backend
+func New(opts Options) (Backend, error) {
+	switch opts.Backend {
+	case BackendMemory:
+		return NewInMemoryCache(opts.TTL, opts.MaxBytes), nil
+	case BackendDisk:
+		return NewDiskCache(opts.Dir, opts.TTL, opts.MaxBytes)
+	case BackendRedis:
+		return NewRedisCache(opts.RedisAddr, opts.RedisPassword, opts.RedisDB, opts.TTL)
+	default:
+		return nil, fmt.Errorf("unknown cache backend: %q", opts.Backend)
 	}
 }
 
-func (c *cache) Get(k string) (string, bool) {
-	v, ok := c.cache[k]
-	return v, ok
+// lookupCounters counts hits and misses, backends embed it and record every Get
+type lookupCounters struct {
+	hits   atomic.Int64
+	misses atomic.Int64
 }
 
-func (c *cache) Set(k string, v string) {
-	c.cache[k] = v
+func (c *lookupCounters) record(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+// stats returns the counters along with the given content totals
+func (c *lookupCounters) stats(entries []Entry) Stats {
+	s := Stats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Entries: len(entries),
+	}
+	for _, e := range entries {
+		s.Bytes += e.Size
+	}
+
+	return s
 }
@@ -2,79 +2,110 @@ package cache_test
 
 import (
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/jponc/domain-crawler/internal/cache"
 	"github.com/stretchr/testify/require"
 )
 
-func TestExtractorCache_Get(t *testing.T) {
-	// Setup cache
-	cache := cache.NewInMemoryCache()
-	cache.Set("http://example.com", "<html>Test</html>")
+func TestNew(t *testing.T) {
+	server := miniredis.RunT(t)
 
 	tests := []struct {
-		name           string
-		key            string
-		expectedValue  string
-		expectedExists bool
+		name          string
+		opts          cache.Options
+		expectedError string
 	}{
 		{
-			name:           "returns no result when key is not in cache",
-			key:            "http://not-found-in-cache.com",
-			expectedValue:  "",
-			expectedExists: false,
+			name: "returns in-memory backend",
+			opts: cache.Options{Backend: cache.BackendMemory, TTL: time.Hour},
+		},
+		{
+			name: "returns disk backend",
+			opts: cache.Options{Backend: cache.BackendDisk, TTL: time.Hour, Dir: t.TempDir()},
+		},
+		{
+			name: "returns redis backend",
+			opts: cache.Options{Backend: cache.BackendRedis, TTL: time.Hour, RedisAddr: server.Addr()},
 		},
 		{
-			name:           "returns value when key is in cache",
-			key:            "http://example.com",
-			expectedValue:  "<html>Test</html>",
-			expectedExists: true,
+			name:          "returns err when backend is unknown",
+			opts:          cache.Options{Backend: "memcached"},
+			expectedError: "unknown cache backend: \"memcached\"",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			value, exists := cache.Get(tt.key)
-			require.Equal(t, tt.expectedValue, value)
-			require.Equal(t, tt.expectedExists, exists)
+			backend, err := cache.New(tt.opts)
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+
+			backend.Set("http://example.com", "<html>Test</html>")
+			value, exists := backend.Get("http://example.com")
+			require.True(t, exists)
+			require.Equal(t, "<html>Test</html>", value)
 		})
 	}
 }
 
-func TestExtractorCache_Set(t *testing.T) {
-	// Setup cache
-	cache := cache.NewInMemoryCache()
-	cache.Set("http://example.com", "<html>Test</html>")
+func TestBackends_Admin(t *testing.T) {
+	server := miniredis.RunT(t)
 
 	tests := []struct {
-		name          string
-		key           string
-		value         string
-		expectedValue string
+		name string
+		opts cache.Options
 	}{
 		{
-			name:          "sets result when key is not in cache",
-			key:           "http://not-found-in-cache.com",
-			value:         "<html>Test 2</html>",
-			expectedValue: "<html>Test 2</html>",
+			name: "in-memory backend",
+			opts: cache.Options{Backend: cache.BackendMemory, TTL: time.Hour},
 		},
 		{
-			name:          "overwrites result when key is in cache",
-			key:           "http://example.com",
-			value:         "<html>Test 3</html>",
-			expectedValue: "<html>Test 3</html>",
+			name: "disk backend",
+			opts: cache.Options{Backend: cache.BackendDisk, TTL: time.Hour, Dir: t.TempDir()},
+		},
+		{
+			name: "redis backend",
+			opts: cache.Options{Backend: cache.BackendRedis, TTL: time.Hour, RedisAddr: server.Addr()},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Set first
-			cache.Set(tt.key, tt.value)
+			backend, err := cache.New(tt.opts)
+			require.NoError(t, err)
 
-			// Then assert on get
-			result, exists := cache.Get(tt.key)
-			require.Equal(t, tt.expectedValue, result)
-			require.True(t, exists)
+			backend.Set("https://a.com/", "aaaa")
+			backend.Set("https://b.com/", "bb")
+
+			_, _ = backend.Get("https://a.com/")
+			_, _ = backend.Get("https://c.com/")
+
+			entries := backend.Entries()
+			require.Len(t, entries, 2)
+			for _, e := range entries {
+				require.Positive(t, e.Size)
+				require.WithinDuration(t, time.Now().Add(time.Hour), e.ExpiresAt, time.Minute)
+				require.WithinDuration(t, time.Now(), e.StoredAt, time.Minute)
+			}
+
+			stats := backend.Stats()
+			require.Equal(t, int64(1), stats.Hits)
+			require.Equal(t, int64(1), stats.Misses)
+			require.Equal(t, 2, stats.Entries)
+			require.Equal(t, entries[0].Size+entries[1].Size, stats.Bytes)
+
+			require.True(t, backend.Delete("https://a.com/"))
+			require.False(t, backend.Delete("https://a.com/"))
+
+			entries = backend.Entries()
+			require.Len(t, entries, 1)
+			require.Equal(t, "https://b.com/", entries[0].Key)
 		})
 	}
 }
@@ -0,0 +1,281 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const diskIndexFilename = "index.json"
+
+// diskEntry describes a cached value stored in its own content file
+type diskEntry struct {
+	File           string    `json:"file"`
+	Size           int64     `json:"size"`
+	StoredAt       time.Time `json:"stored_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	LastAccessedAt time.Time `json:"last_accessed_at"`
+}
+
+type diskCache struct {
+	lookupCounters
+	mu        sync.Mutex
+	dir       string
+	ttl       time.Duration
+	maxBytes  int64
+	usedBytes int64
+	index     map[string]*diskEntry
+	logger    zerolog.Logger
+}
+
+// NewDiskCache returns a cache which stores every value in a content file under dir,
+// along with an index file so the cache survives restarts.
+// Expiry and eviction work the same way as the in-memory cache, maxBytes budgets the content files.
+func NewDiskCache(dir string, ttl time.Duration, maxBytes int64) (*diskCache, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+
+	c := &diskCache{
+		dir:      dir,
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		index:    make(map[string]*diskEntry),
+		logger:   log.With().Str("package", "cache").Str("cache", "DiskCache").Logger(),
+	}
+
+	err = c.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *diskCache) Get(k string) (string, bool) {
+	v, ok := c.get(k)
+	c.record(ok)
+
+	return v, ok
+}
+
+func (c *diskCache) get(k string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.index[k]
+	if !ok {
+		return "", false
+	}
+
+	if !e.ExpiresAt.IsZero() && time.Now().After(e.ExpiresAt) {
+		c.remove(k)
+		c.saveIndex()
+		return "", false
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.dir, e.File))
+	if err != nil {
+		c.logger.Error().Err(err).Str("key", k).Msg("Failed to read cache file")
+		c.remove(k)
+		c.saveIndex()
+		return "", false
+	}
+
+	// Access times are persisted along with the next write to the index
+	e.LastAccessedAt = time.Now()
+
+	return string(data), true
+}
+
+func (c *diskCache) Set(k string, v string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	defer c.saveIndex()
+
+	if _, ok := c.index[k]; ok {
+		c.remove(k)
+	}
+
+	// Values which can never fit are not cached at all
+	size := int64(len(v))
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+
+	e := &diskEntry{
+		File:           contentFilename(k),
+		Size:           size,
+		StoredAt:       time.Now(),
+		LastAccessedAt: time.Now(),
+	}
+	if c.ttl > 0 {
+		e.ExpiresAt = time.Now().Add(c.ttl)
+	}
+
+	err := writeFileAtomic(filepath.Join(c.dir, e.File), []byte(v))
+	if err != nil {
+		c.logger.Error().Err(err).Str("key", k).Msg("Failed to write cache file")
+		return
+	}
+
+	c.index[k] = e
+	c.usedBytes += e.Size
+
+	// Evict least recently used entries until we're back under budget
+	for c.maxBytes > 0 && c.usedBytes > c.maxBytes {
+		c.remove(c.leastRecentlyUsed())
+	}
+}
+
+func (c *diskCache) Delete(k string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.index[k]; !ok {
+		return false
+	}
+
+	c.remove(k)
+	c.saveIndex()
+	return true
+}
+
+// Entries returns the live entries sorted by key
+func (c *diskCache) Entries() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entries := make([]Entry, 0, len(c.index))
+	for k, e := range c.index {
+		if !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt) {
+			continue
+		}
+
+		entries = append(entries, Entry{
+			Key:       k,
+			Size:      e.Size,
+			StoredAt:  e.StoredAt,
+			ExpiresAt: e.ExpiresAt,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	return entries
+}
+
+func (c *diskCache) Stats() Stats {
+	return c.stats(c.Entries())
+}
+
+// leastRecentlyUsed returns the key accessed the longest time ago, the caller must hold c.mu
+func (c *diskCache) leastRecentlyUsed() string {
+	var lruKey string
+	var lruEntry *diskEntry
+	for k, e := range c.index {
+		if lruEntry == nil || e.LastAccessedAt.Before(lruEntry.LastAccessedAt) {
+			lruKey, lruEntry = k, e
+		}
+	}
+
+	return lruKey
+}
+
+// remove deletes an entry and its content file, the caller must hold c.mu
+func (c *diskCache) remove(k string) {
+	e, ok := c.index[k]
+	if !ok {
+		return
+	}
+
+	err := os.Remove(filepath.Join(c.dir, e.File))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		c.logger.Error().Err(err).Str("key", k).Msg("Failed to remove cache file")
+	}
+
+	delete(c.index, k)
+	c.usedBytes -= e.Size
+}
+
+// loadIndex restores the index written by a previous run, dropping entries that expired or lost their content file
+func (c *diskCache) loadIndex() error {
+	data, err := os.ReadFile(filepath.Join(c.dir, diskIndexFilename))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read cache index: %w", err)
+	}
+
+	index := map[string]*diskEntry{}
+	err = json.Unmarshal(data, &index)
+	if err != nil {
+		// A corrupted index only costs us the cached documents
+		c.logger.Error().Err(err).Msg("Failed to parse cache index, starting with an empty cache")
+		return nil
+	}
+
+	for k, e := range index {
+		if !e.ExpiresAt.IsZero() && time.Now().After(e.ExpiresAt) {
+			_ = os.Remove(filepath.Join(c.dir, e.File))
+			continue
+		}
+
+		if _, err := os.Stat(filepath.Join(c.dir, e.File)); err != nil {
+			continue
+		}
+
+		c.index[k] = e
+		c.usedBytes += e.Size
+	}
+
+	return nil
+}
+
+// saveIndex persists the index, the caller must hold c.mu
+func (c *diskCache) saveIndex() {
+	data, err := json.Marshal(c.index)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Failed to encode cache index")
+		return
+	}
+
+	err = writeFileAtomic(filepath.Join(c.dir, diskIndexFilename), data)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Failed to write cache index")
+	}
+}
+
+// contentFilename derives a file name from the key, as keys are URLs which aren't safe to use as is
+func contentFilename(k string) string {
+	sum := sha256.Sum256([]byte(k))
+	return hex.EncodeToString(sum[:]) + ".cache"
+}
+
+// writeFileAtomic writes to a temporary file first so readers never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	err := os.WriteFile(tmp, data, 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,95 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskCache_GetSet(t *testing.T) {
+	diskCache, err := cache.NewDiskCache(t.TempDir(), time.Hour, 1024)
+	require.NoError(t, err)
+
+	_, exists := diskCache.Get("http://example.com")
+	require.False(t, exists)
+
+	diskCache.Set("http://example.com", "<html>Test</html>")
+	value, exists := diskCache.Get("http://example.com")
+	require.True(t, exists)
+	require.Equal(t, "<html>Test</html>", value)
+
+	diskCache.Set("http://example.com", "<html>Test 2</html>")
+	value, exists = diskCache.Get("http://example.com")
+	require.True(t, exists)
+	require.Equal(t, "<html>Test 2</html>", value)
+}
+
+func TestDiskCache_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	diskCache, err := cache.NewDiskCache(dir, time.Hour, 1024)
+	require.NoError(t, err)
+	diskCache.Set("http://example.com", "<html>Test</html>")
+
+	// A new instance on the same dir picks up the previous entries
+	restarted, err := cache.NewDiskCache(dir, time.Hour, 1024)
+	require.NoError(t, err)
+
+	value, exists := restarted.Get("http://example.com")
+	require.True(t, exists)
+	require.Equal(t, "<html>Test</html>", value)
+}
+
+func TestDiskCache_IgnoresCorruptedIndex(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.json"), []byte("not json"), 0o644))
+
+	diskCache, err := cache.NewDiskCache(dir, time.Hour, 1024)
+	require.NoError(t, err)
+
+	_, exists := diskCache.Get("http://example.com")
+	require.False(t, exists)
+}
+
+func TestDiskCache_TTL(t *testing.T) {
+	diskCache, err := cache.NewDiskCache(t.TempDir(), 20*time.Millisecond, 1024)
+	require.NoError(t, err)
+	diskCache.Set("http://example.com", "<html>Test</html>")
+
+	time.Sleep(30 * time.Millisecond)
+
+	_, exists := diskCache.Get("http://example.com")
+	require.False(t, exists)
+}
+
+func TestDiskCache_Eviction(t *testing.T) {
+	dir := t.TempDir()
+	diskCache, err := cache.NewDiskCache(dir, time.Hour, 20)
+	require.NoError(t, err)
+
+	diskCache.Set("k1", "1234567890")
+	time.Sleep(time.Millisecond)
+	diskCache.Set("k2", "1234567890")
+	time.Sleep(time.Millisecond)
+	diskCache.Get("k1")
+	time.Sleep(time.Millisecond)
+	diskCache.Set("k3", "1234567890")
+
+	_, exists := diskCache.Get("k2")
+	require.False(t, exists)
+
+	_, exists = diskCache.Get("k1")
+	require.True(t, exists)
+
+	_, exists = diskCache.Get("k3")
+	require.True(t, exists)
+
+	// Only the index and the two remaining content files are left
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// entry is a cached value along with when it expires
+type entry struct {
+	key       string
+	value     string
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+func (e *entry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+type inMemoryCache struct {
+	lookupCounters
+	mu        sync.Mutex
+	ttl       time.Duration
+	maxBytes  int64
+	usedBytes int64
+	items     map[string]*list.Element
+	lru       *list.List
+}
+
+// NewInMemoryCache returns a thread safe LRU cache.
+// Entries expire ttl after they are set, and the least recently used entries are evicted
+// once the total size of the cached keys and values goes over maxBytes.
+// A zero ttl or maxBytes disables the corresponding limit.
+func NewInMemoryCache(ttl time.Duration, maxBytes int64) *inMemoryCache {
+	return &inMemoryCache{
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (c *inMemoryCache) Get(k string) (string, bool) {
+	v, ok := c.get(k)
+	c.record(ok)
+
+	return v, ok
+}
+
+func (c *inMemoryCache) get(k string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[k]
+	if !ok {
+		return "", false
+	}
+
+	e := el.Value.(*entry)
+	if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+		c.removeElement(el)
+		return "", false
+	}
+
+	c.lru.MoveToFront(el)
+	return e.value, true
+}
+
+func (c *inMemoryCache) Set(k string, v string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := &entry{key: k, value: v, storedAt: time.Now()}
+	if c.ttl > 0 {
+		e.expiresAt = time.Now().Add(c.ttl)
+	}
+
+	if el, ok := c.items[k]; ok {
+		c.removeElement(el)
+	}
+
+	// Values which can never fit are not cached at all
+	if c.maxBytes > 0 && e.size() > c.maxBytes {
+		return
+	}
+
+	c.items[k] = c.lru.PushFront(e)
+	c.usedBytes += e.size()
+
+	// Evict least recently used entries until we're back under budget
+	for c.maxBytes > 0 && c.usedBytes > c.maxBytes {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+func (c *inMemoryCache) Delete(k string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[k]
+	if !ok {
+		return false
+	}
+
+	c.removeElement(el)
+	return true
+}
+
+// Entries returns the live entries from the most to the least recently used
+func (c *inMemoryCache) Entries() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entries := make([]Entry, 0, len(c.items))
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*entry)
+		if !e.expiresAt.IsZero() && now.After(e.expiresAt) {
+			continue
+		}
+
+		entries = append(entries, Entry{
+			Key:       e.key,
+			Size:      e.size(),
+			StoredAt:  e.storedAt,
+			ExpiresAt: e.expiresAt,
+		})
+	}
+
+	return entries
+}
+
+func (c *inMemoryCache) Stats() Stats {
+	return c.stats(c.Entries())
+}
+
+// removeElement drops an entry from the cache, the caller must hold c.mu
+func (c *inMemoryCache) removeElement(el *list.Element) {
+	e := el.Value.(*entry)
+	c.lru.Remove(el)
+	delete(c.items, e.key)
+	c.usedBytes -= e.size()
+}
@@ -0,0 +1,174 @@
+package cache_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractorCache_Get(t *testing.T) {
+	// Setup cache
+	cache := cache.NewInMemoryCache(time.Hour, 1024)
+	cache.Set("http://example.com", "<html>Test</html>")
+
+	tests := []struct {
+		name           string
+		key            string
+		expectedValue  string
+		expectedExists bool
+	}{
+		{
+			name:           "returns no result when key is not in cache",
+			key:            "http://not-found-in-cache.com",
+			expectedValue:  "",
+			expectedExists: false,
+		},
+		{
+			name:           "returns value when key is in cache",
+			key:            "http://example.com",
+			expectedValue:  "<html>Test</html>",
+			expectedExists: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, exists := cache.Get(tt.key)
+			require.Equal(t, tt.expectedValue, value)
+			require.Equal(t, tt.expectedExists, exists)
+		})
+	}
+}
+
+func TestExtractorCache_Set(t *testing.T) {
+	// Setup cache
+	cache := cache.NewInMemoryCache(time.Hour, 1024)
+	cache.Set("http://example.com", "<html>Test</html>")
+
+	tests := []struct {
+		name          string
+		key           string
+		value         string
+		expectedValue string
+	}{
+		{
+			name:          "sets result when key is not in cache",
+			key:           "http://not-found-in-cache.com",
+			value:         "<html>Test 2</html>",
+			expectedValue: "<html>Test 2</html>",
+		},
+		{
+			name:          "overwrites result when key is in cache",
+			key:           "http://example.com",
+			value:         "<html>Test 3</html>",
+			expectedValue: "<html>Test 3</html>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Set first
+			cache.Set(tt.key, tt.value)
+
+			// Then assert on get
+			result, exists := cache.Get(tt.key)
+			require.Equal(t, tt.expectedValue, result)
+			require.True(t, exists)
+		})
+	}
+}
+
+func TestExtractorCache_Eviction(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxBytes        int64
+		operations      []string
+		expectedPresent []string
+		expectedMissing []string
+	}{
+		{
+			name:            "evicts least recently used entry when over budget",
+			maxBytes:        20,
+			operations:      []string{"set k1", "set k2", "set k3"},
+			expectedPresent: []string{"k2", "k3"},
+			expectedMissing: []string{"k1"},
+		},
+		{
+			name:            "keeps recently read entries",
+			maxBytes:        20,
+			operations:      []string{"set k1", "set k2", "get k1", "set k3"},
+			expectedPresent: []string{"k1", "k3"},
+			expectedMissing: []string{"k2"},
+		},
+		{
+			name:            "doesn't cache values larger than the budget",
+			maxBytes:        5,
+			operations:      []string{"set k1"},
+			expectedMissing: []string{"k1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Every entry is 10 bytes, 2 for the key and 8 for the value
+			cache := cache.NewInMemoryCache(time.Hour, tt.maxBytes)
+
+			for _, operation := range tt.operations {
+				var op, k string
+				_, err := fmt.Sscan(operation, &op, &k)
+				require.NoError(t, err)
+
+				if op == "set" {
+					cache.Set(k, "12345678")
+				} else {
+					cache.Get(k)
+				}
+			}
+
+			for _, k := range tt.expectedPresent {
+				_, exists := cache.Get(k)
+				require.True(t, exists, k)
+			}
+
+			for _, k := range tt.expectedMissing {
+				_, exists := cache.Get(k)
+				require.False(t, exists, k)
+			}
+		})
+	}
+}
+
+func TestExtractorCache_TTL(t *testing.T) {
+	cache := cache.NewInMemoryCache(20*time.Millisecond, 1024)
+	cache.Set("http://example.com", "<html>Test</html>")
+
+	_, exists := cache.Get("http://example.com")
+	require.True(t, exists)
+
+	time.Sleep(30 * time.Millisecond)
+
+	_, exists = cache.Get("http://example.com")
+	require.False(t, exists)
+}
+
+func TestExtractorCache_Concurrency(t *testing.T) {
+	cache := cache.NewInMemoryCache(time.Hour, 1024)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				k := fmt.Sprintf("http://example.com/%d", j%20)
+				cache.Set(k, fmt.Sprintf("<html>%d</html>", i))
+				cache.Get(k)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
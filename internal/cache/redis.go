@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	redisKeyPrefix = "domaincrawler:html:"
+	redisTimeout   = 2 * time.Second
+	redisScanCount = 100
+)
+
+// redisCache counts hits and misses per process, they are not shared between replicas
+type redisCache struct {
+	lookupCounters
+	client *redis.Client
+	ttl    time.Duration
+	logger zerolog.Logger
+}
+
+// NewRedisCache returns a cache backed by any server speaking the Redis protocol.
+// Entries expire ttl after they are set, eviction is left to the server's maxmemory policy.
+func NewRedisCache(addr string, password string, db int, ttl time.Duration) (*redisCache, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	err := client.Ping(ctx).Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &redisCache{
+		client: client,
+		ttl:    ttl,
+		logger: log.With().Str("package", "cache").Str("cache", "RedisCache").Logger(),
+	}, nil
+}
+
+func (c *redisCache) Get(k string) (string, bool) {
+	v, ok := c.get(k)
+	c.record(ok)
+
+	return v, ok
+}
+
+func (c *redisCache) get(k string) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	v, err := c.client.Get(ctx, redisKeyPrefix+k).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false
+	}
+	if err != nil {
+		// An unavailable cache is treated as a miss so crawls keep working
+		c.logger.Error().Err(err).Str("key", k).Msg("Failed to get value from redis")
+		return "", false
+	}
+
+	return v, true
+}
+
+func (c *redisCache) Set(k string, v string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	err := c.client.Set(ctx, redisKeyPrefix+k, v, c.ttl).Err()
+	if err != nil {
+		c.logger.Error().Err(err).Str("key", k).Msg("Failed to set value in redis")
+	}
+}
+
+func (c *redisCache) Delete(k string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	deleted, err := c.client.Del(ctx, redisKeyPrefix+k).Result()
+	if err != nil {
+		c.logger.Error().Err(err).Str("key", k).Msg("Failed to delete value from redis")
+		return false
+	}
+
+	return deleted > 0
+}
+
+// Entries scans the namespaced keys, sorted by key.
+// Redis doesn't keep when a key was set, so StoredAt is derived from the remaining TTL and is zero without a TTL.
+func (c *redisCache) Entries() []Entry {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	keys := []string{}
+	iter := c.client.Scan(ctx, 0, redisKeyPrefix+"*", redisScanCount).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		c.logger.Error().Err(err).Msg("Failed to scan keys in redis")
+		return []Entry{}
+	}
+
+	pipe := c.client.Pipeline()
+	sizes := make([]*redis.IntCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		sizes[i] = pipe.StrLen(ctx, key)
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("Failed to get entries from redis")
+		return []Entry{}
+	}
+
+	now := time.Now()
+	entries := make([]Entry, 0, len(keys))
+	for i, key := range keys {
+		// Keys which expired in between the scan and the pipeline have no size left
+		size := sizes[i].Val()
+		if size == 0 {
+			continue
+		}
+
+		e := Entry{
+			Key:  strings.TrimPrefix(key, redisKeyPrefix),
+			Size: size,
+		}
+
+		if remaining := ttls[i].Val(); remaining > 0 {
+			e.ExpiresAt = now.Add(remaining)
+			if c.ttl > 0 {
+				e.StoredAt = e.ExpiresAt.Add(-c.ttl)
+			}
+		}
+
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	return entries
+}
+
+func (c *redisCache) Stats() Stats {
+	return c.stats(c.Entries())
+}
@@ -0,0 +1,55 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jponc/domain-crawler/internal/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisCache_GetSet(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	redisCache, err := cache.NewRedisCache(server.Addr(), "", 0, time.Hour)
+	require.NoError(t, err)
+
+	_, exists := redisCache.Get("http://example.com")
+	require.False(t, exists)
+
+	redisCache.Set("http://example.com", "<html>Test</html>")
+	value, exists := redisCache.Get("http://example.com")
+	require.True(t, exists)
+	require.Equal(t, "<html>Test</html>", value)
+
+	// Keys are namespaced and expire with the configured TTL
+	require.True(t, server.Exists("domaincrawler:html:http://example.com"))
+	require.Equal(t, time.Hour, server.TTL("domaincrawler:html:http://example.com"))
+}
+
+func TestRedisCache_TTL(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	redisCache, err := cache.NewRedisCache(server.Addr(), "", 0, time.Minute)
+	require.NoError(t, err)
+	redisCache.Set("http://example.com", "<html>Test</html>")
+
+	server.FastForward(2 * time.Minute)
+
+	_, exists := redisCache.Get("http://example.com")
+	require.False(t, exists)
+}
+
+func TestRedisCache_TreatsUnavailableServerAsMiss(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	redisCache, err := cache.NewRedisCache(server.Addr(), "", 0, time.Hour)
+	require.NoError(t, err)
+	redisCache.Set("http://example.com", "<html>Test</html>")
+
+	server.Close()
+
+	_, exists := redisCache.Get("http://example.com")
+	require.False(t, exists)
+}
@@ -2,14 +2,51 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
 
 type config struct {
-	Port                     string `envconfig:"PORT" default:"8080"`
-	ExtractorConcurrentLimit int    `envconfig:"EXTRACTOR_CONCURRENT_LIMIT" default:"2"`
-	RateLimitRPM             int    `envconfig:"RATE_LIMIT_RPM" default:"60"`
+	Port                      string            `envconfig:"PORT" default:"8080"`
+	ExtractorConcurrentLimit  int               `envconfig:"EXTRACTOR_CONCURRENT_LIMIT" default:"2"`
+	RateLimitRPM              int               `envconfig:"RATE_LIMIT_RPM" default:"60"`
+	JobsConcurrentLimit       int               `envconfig:"JOBS_CONCURRENT_LIMIT" default:"2"`
+	JobsRetention             time.Duration     `envconfig:"JOBS_RETENTION" default:"1h"`
+	CacheTTL                  time.Duration     `envconfig:"CACHE_TTL" default:"1h"`
+	CacheMaxBytes             int64             `envconfig:"CACHE_MAX_BYTES" default:"104857600"`
+	CacheBackend              string            `envconfig:"CACHE_BACKEND" default:"memory"`
+	CacheDir                  string            `envconfig:"CACHE_DIR" default:"data/cache"`
+	CacheRedisAddr            string            `envconfig:"CACHE_REDIS_ADDR" default:"localhost:6379"`
+	CacheRedisPassword        string            `envconfig:"CACHE_REDIS_PASSWORD"`
+	CacheRedisDB              int               `envconfig:"CACHE_REDIS_DB" default:"0"`
+	AdminToken                string            `envconfig:"ADMIN_TOKEN"`
+	LinkCheckConcurrentLimit  int               `envconfig:"LINK_CHECK_CONCURRENT_LIMIT" default:"10"`
+	LinkCheckTimeout          time.Duration     `envconfig:"LINK_CHECK_TIMEOUT" default:"10s"`
+	RobotsUserAgent           string            `envconfig:"ROBOTS_USER_AGENT" default:"domaincrawler"`
+	RobotsCacheTTL            time.Duration     `envconfig:"ROBOTS_CACHE_TTL" default:"24h"`
+	HostConcurrentLimit       int               `envconfig:"HOST_CONCURRENT_LIMIT" default:"2"`
+	HostRequestDelay          time.Duration     `envconfig:"HOST_REQUEST_DELAY" default:"500ms"`
+	RetryMaxAttempts          int               `envconfig:"RETRY_MAX_ATTEMPTS" default:"3"`
+	RetryBaseDelay            time.Duration     `envconfig:"RETRY_BASE_DELAY" default:"500ms"`
+	RetryMaxDelay             time.Duration     `envconfig:"RETRY_MAX_DELAY" default:"10s"`
+	BreakerFailureThreshold   int               `envconfig:"BREAKER_FAILURE_THRESHOLD" default:"5"`
+	BreakerOpenDuration       time.Duration     `envconfig:"BREAKER_OPEN_DURATION" default:"30s"`
+	ExtractorMaxBodyBytes     int64             `envconfig:"EXTRACTOR_MAX_BODY_BYTES" default:"10485760"`
+	HTTPConnectTimeout        time.Duration     `envconfig:"HTTP_CONNECT_TIMEOUT" default:"10s"`
+	HTTPTLSHandshakeTimeout   time.Duration     `envconfig:"HTTP_TLS_HANDSHAKE_TIMEOUT" default:"10s"`
+	HTTPResponseHeaderTimeout time.Duration     `envconfig:"HTTP_RESPONSE_HEADER_TIMEOUT" default:"15s"`
+	HTTPTimeout               time.Duration     `envconfig:"HTTP_TIMEOUT" default:"30s"`
+	HTTPUserAgent             string            `envconfig:"HTTP_USER_AGENT" default:"domaincrawler/1.0"`
+	HTTPHeaders               map[string]string `envconfig:"HTTP_HEADERS"`
+	HTTPProxyURL              string            `envconfig:"HTTP_PROXY_URL"`
+	HTTPMaxIdleConns          int               `envconfig:"HTTP_MAX_IDLE_CONNS" default:"100"`
+	HTTPMaxIdleConnsPerHost   int               `envconfig:"HTTP_MAX_IDLE_CONNS_PER_HOST" default:"10"`
+	URLAllowedHosts           []string          `envconfig:"URL_ALLOWED_HOSTS"`
+	URLDeniedHosts            []string          `envconfig:"URL_DENIED_HOSTS"`
+	URLAllowedCIDRs           []string          `envconfig:"URL_ALLOWED_CIDRS"`
+	URLDeniedCIDRs            []string          `envconfig:"URL_DENIED_CIDRS"`
+	HTTPIdleConnTimeout       time.Duration     `envconfig:"HTTP_IDLE_CONN_TIMEOUT" default:"90s"`
 }
 
 func GetConfig() (*config, error) {
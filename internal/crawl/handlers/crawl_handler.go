@@ -11,7 +11,7 @@ import (
 )
 
 type crawlService interface {
-	Crawl(ctx context.Context, urls []string, keywords []string) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error)
+	Crawl(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error)
 }
 
 type crawlHandler struct {
@@ -41,11 +41,25 @@ func (h *crawlHandler) Crawl(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = validateKeywordOptions(reqBody)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errResp := errs.ErrorResponse{Error: err.Error()}
+		_ = json.NewEncoder(w).Encode(errResp)
+		return
+	}
+
 	// Remove duplicate urls if any
 	uniqueURLs := utils.RemoveDuplicates(reqBody.URLs)
 
+	// Stream results as they are produced when the client asks for it
+	if contentType, ok := negotiateStream(r); ok {
+		h.streamCrawl(w, r, contentType, uniqueURLs, reqBody)
+		return
+	}
+
 	// Crawl the URLs
-	successCrawlResults, errorCrawlResults, err := h.crawlService.Crawl(ctx, uniqueURLs, reqBody.Keywords)
+	successCrawlResults, errorCrawlResults, err := h.crawlService.Crawl(ctx, uniqueURLs, reqBody.Keywords, convertCrawlRequestToCrawlOptions(reqBody))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		errResp := errs.ErrorResponse{Error: err.Error()}
@@ -61,10 +75,36 @@ func (h *crawlHandler) Crawl(w http.ResponseWriter, r *http.Request) {
 
 	// Create response Body
 	respBody := CrawlResponse{
-		Results: successResults,
-		Errors:  errorResults,
+		Results:     successResults,
+		Errors:      errorResults,
+		LinkSummary: convertLinkCheckSummary(successCrawlResults),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(respBody)
 }
+
+func (h *crawlHandler) streamCrawl(w http.ResponseWriter, r *http.Request, contentType string, urls []string, reqBody CrawlRequest) {
+	stream := newStreamWriter(w, contentType)
+	summary := CrawlSummary{}
+
+	// Write every result as soon as the crawl service produces it
+	opts := convertCrawlRequestToCrawlOptions(reqBody)
+	opts.OnSuccess = func(crawlResult services.SuccessCrawlResult) {
+		summary.Succeeded++
+		stream.writeEvent(streamEventResult, convertSuccessCrawlResultToSuccessResult(crawlResult))
+	}
+	opts.OnError = func(crawlResult services.ErrorCrawlResult) {
+		summary.Failed++
+		stream.writeEvent(streamEventError, convertErrorCrawlResultToErrorResult(crawlResult))
+	}
+
+	successCrawlResults, _, err := h.crawlService.Crawl(r.Context(), urls, reqBody.Keywords, opts)
+	if err != nil {
+		// Headers are already sent, so the failure is reported in the summary
+		summary.Error = err.Error()
+	}
+	summary.LinkSummary = convertLinkCheckSummary(successCrawlResults)
+
+	stream.writeEvent(streamEventSummary, summary)
+}
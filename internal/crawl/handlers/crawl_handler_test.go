@@ -7,12 +7,14 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi/v5"
 	"github.com/jponc/domain-crawler/api/openapi"
 	"github.com/jponc/domain-crawler/internal/crawl/handlers"
 	"github.com/jponc/domain-crawler/internal/crawl/services"
+	"github.com/jponc/domain-crawler/internal/extractor"
 	"github.com/jponc/domain-crawler/internal/middlewares"
 	"github.com/kinbiko/jsonassert"
 	"github.com/stretchr/testify/require"
@@ -20,12 +22,12 @@ import (
 
 // Mocks
 type mockCrawlService struct {
-	crawlFn func(ctx context.Context, urls []string, keywords []string) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error)
+	crawlFn func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error)
 }
 
-func (m *mockCrawlService) Crawl(ctx context.Context, urls []string, keywords []string) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+func (m *mockCrawlService) Crawl(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
 	if m != nil && m.crawlFn != nil {
-		return m.crawlFn(ctx, urls, keywords)
+		return m.crawlFn(ctx, urls, keywords, opts)
 	}
 
 	return []services.SuccessCrawlResult{}, []services.ErrorCrawlResult{}, nil
@@ -67,7 +69,7 @@ func TestCrawlHandler_Crawl(t *testing.T) {
 					"keywords": ["example"]
 				}`,
 			mockCrawlService: &mockCrawlService{
-				crawlFn: func(ctx context.Context, urls []string, keywords []string) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
 					return nil, nil, fmt.Errorf("error")
 				},
 			},
@@ -85,13 +87,16 @@ func TestCrawlHandler_Crawl(t *testing.T) {
 					"keywords": ["example"]
 				}`,
 			mockCrawlService: &mockCrawlService{
-				crawlFn: func(ctx context.Context, urls []string, keywords []string) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
 					return []services.SuccessCrawlResult{
 						{
 							URL:              "https://example.com",
 							Title:            "Title",
 							MetaDescriptions: []string{"Meta Description 1", "Meta Description 2"},
-							Links:            []string{"https://link1.com", "https://link2.com"},
+							Links: []services.Link{
+								{URL: "https://link1.com/", Text: "Link 1", Rel: []string{}, Class: "external"},
+								{URL: "https://example.com/about", Text: "About", Rel: []string{"nofollow"}, Class: "internal"},
+							},
 							KeywordCounts: map[string]int{
 								"keyword1": 1,
 								"keyword2": 2,
@@ -108,11 +113,15 @@ func TestCrawlHandler_Crawl(t *testing.T) {
 							"url": "https://example.com",
 							"title": "Title",
 							"meta_descriptions": ["Meta Description 1", "Meta Description 2"],
-							"links": ["https://link1.com", "https://link2.com"],
+							"links": [
+								{"url": "https://link1.com/", "text": "Link 1", "rel": [], "class": "external"},
+								{"url": "https://example.com/about", "text": "About", "rel": ["nofollow"], "class": "internal"}
+							],
 							"keyword_counts": {
 								"keyword1": 1,
 								"keyword2": 2
-							}
+							},
+							"depth": 0
 						}
 					]
 				}`,
@@ -125,7 +134,7 @@ func TestCrawlHandler_Crawl(t *testing.T) {
 					"keywords": ["example"]
 				}`,
 			mockCrawlService: &mockCrawlService{
-				crawlFn: func(ctx context.Context, urls []string, keywords []string) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
 					require.Len(t, urls, 1)
 					require.Equal(t, []string{"https://example.com"}, urls)
 
@@ -134,7 +143,10 @@ func TestCrawlHandler_Crawl(t *testing.T) {
 							URL:              "https://example.com",
 							Title:            "Title",
 							MetaDescriptions: []string{"Meta Description 1", "Meta Description 2"},
-							Links:            []string{"https://link1.com", "https://link2.com"},
+							Links: []services.Link{
+								{URL: "https://link1.com/", Text: "Link 1", Rel: []string{}, Class: "external"},
+								{URL: "https://example.com/about", Text: "About", Rel: []string{"nofollow"}, Class: "internal"},
+							},
 							KeywordCounts: map[string]int{
 								"keyword1": 1,
 								"keyword2": 2,
@@ -151,11 +163,15 @@ func TestCrawlHandler_Crawl(t *testing.T) {
 							"url": "https://example.com",
 							"title": "Title",
 							"meta_descriptions": ["Meta Description 1", "Meta Description 2"],
-							"links": ["https://link1.com", "https://link2.com"],
+							"links": [
+								{"url": "https://link1.com/", "text": "Link 1", "rel": [], "class": "external"},
+								{"url": "https://example.com/about", "text": "About", "rel": ["nofollow"], "class": "internal"}
+							],
 							"keyword_counts": {
 								"keyword1": 1,
 								"keyword2": 2
-							}
+							},
+							"depth": 0
 						}
 					]
 				}`,
@@ -168,13 +184,16 @@ func TestCrawlHandler_Crawl(t *testing.T) {
 					"keywords": ["example"]
 				}`,
 			mockCrawlService: &mockCrawlService{
-				crawlFn: func(ctx context.Context, urls []string, keywords []string) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
 					successCrawlResults := []services.SuccessCrawlResult{
 						{
 							URL:              "https://example.com",
 							Title:            "Title",
 							MetaDescriptions: []string{"Meta Description 1", "Meta Description 2"},
-							Links:            []string{"https://link1.com", "https://link2.com"},
+							Links: []services.Link{
+								{URL: "https://link1.com/", Text: "Link 1", Rel: []string{}, Class: "external"},
+								{URL: "https://example.com/about", Text: "About", Rel: []string{"nofollow"}, Class: "internal"},
+							},
 							KeywordCounts: map[string]int{
 								"keyword1": 1,
 								"keyword2": 2,
@@ -200,21 +219,648 @@ func TestCrawlHandler_Crawl(t *testing.T) {
 							"url": "https://example.com",
 							"title": "Title",
 							"meta_descriptions": ["Meta Description 1", "Meta Description 2"],
-							"links": ["https://link1.com", "https://link2.com"],
+							"links": [
+								{"url": "https://link1.com/", "text": "Link 1", "rel": [], "class": "external"},
+								{"url": "https://example.com/about", "text": "About", "rel": ["nofollow"], "class": "internal"}
+							],
 							"keyword_counts": {
 								"keyword1": 1,
 								"keyword2": 2
-							}
+							},
+							"depth": 0
 						}
 					],
 					"errors": [
 						{
 							"url": "https://example.com/404",
-							"error": "failed to extract data"
+							"error": "failed to extract data",
+							"depth": 0
+						}
+					]
+				}`,
+		},
+		{
+			name: "passes recursive crawl options to crawl service",
+			requestBody: `
+				{
+					"urls": ["https://example.com"],
+					"keywords": ["example"],
+					"recursive": true,
+					"max_depth": 3,
+					"max_pages": 20,
+					"strip_tracking_params": true,
+					"ignore_robots": true
+				}`,
+			mockCrawlService: &mockCrawlService{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					require.Equal(t, services.CrawlOptions{Recursive: true, MaxDepth: 3, MaxPages: 20, StripTrackingParams: true, Redirects: extractor.RedirectPolicy{MaxRedirects: 10}, IgnoreRobots: true}, opts)
+
+					return []services.SuccessCrawlResult{
+						{
+							URL:              "https://example.com/about",
+							Title:            "About",
+							MetaDescriptions: []string{},
+							Links:            []services.Link{},
+							KeywordCounts:    map[string]int{},
+							Depth:            1,
+							DiscoveredFrom:   "https://example.com",
+						},
+					}, nil, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"results": [
+						{
+							"url": "https://example.com/about",
+							"title": "About",
+							"meta_descriptions": [],
+							"links": [],
+							"keyword_counts": {},
+							"depth": 1,
+							"discovered_from": "https://example.com"
+						}
+					]
+				}`,
+		},
+		{
+			name: "passes the cache policy to crawl service and returns the cache age",
+			requestBody: `
+				{
+					"urls": ["https://example.com"],
+					"keywords": ["example"],
+					"cache": {"mode": "use", "max_age_seconds": 300}
+				}`,
+			mockCrawlService: &mockCrawlService{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					maxAge := 5 * time.Minute
+					require.Equal(t, extractor.CachePolicy{Mode: extractor.CacheModeUse, MaxAge: &maxAge}, opts.Cache)
+
+					cacheAge := 90 * time.Second
+					return []services.SuccessCrawlResult{
+						{
+							URL:              "https://example.com",
+							Title:            "Example",
+							MetaDescriptions: []string{},
+							Links:            []services.Link{},
+							KeywordCounts:    map[string]int{},
+							Source:           "cache",
+							CacheAge:         &cacheAge,
+						},
+					}, nil, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"results": [
+						{
+							"url": "https://example.com",
+							"title": "Example",
+							"meta_descriptions": [],
+							"links": [],
+							"keyword_counts": {},
+							"source": "cache",
+							"cache_age_seconds": 90,
+							"depth": 0
+						}
+					]
+				}`,
+		},
+		{
+			name: "passes the redirect policy to crawl service and returns redirect chains",
+			requestBody: `
+				{
+					"urls": ["http://example.com", "http://example.com/loop"],
+					"keywords": ["example"],
+					"follow_redirects": true,
+					"max_redirects": 5
+				}`,
+			mockCrawlService: &mockCrawlService{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					require.Equal(t, extractor.RedirectPolicy{MaxRedirects: 5}, opts.Redirects)
+
+					return []services.SuccessCrawlResult{
+						{
+							URL:              "http://example.com",
+							FinalURL:         "http://example.com/home",
+							Redirects:        []services.Redirect{{URL: "http://example.com", StatusCode: 301, Location: "https://example.com/home"}, {URL: "https://example.com/home", StatusCode: 302, Location: "http://example.com/home", Downgrade: true}},
+							HTTPSDowngrade:   true,
+							MetaDescriptions: []string{},
+							Links:            []services.Link{},
+							KeywordCounts:    map[string]int{},
+						},
+					}, []services.ErrorCrawlResult{
+						{
+							URL:       "http://example.com/loop",
+							Error:     "redirect loop after 1 redirect(s)",
+							Reason:    services.ErrorReasonRedirectLoop,
+							Redirects: []services.Redirect{{URL: "http://example.com/loop", StatusCode: 302, Location: "/loop"}},
+						},
+					}, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"results": [
+						{
+							"url": "http://example.com",
+							"final_url": "http://example.com/home",
+							"redirects": [
+								{"url": "http://example.com", "status_code": 301, "location": "https://example.com/home"},
+								{"url": "https://example.com/home", "status_code": 302, "location": "http://example.com/home", "downgrade": true}
+							],
+							"https_downgrade": true,
+							"title": "",
+							"meta_descriptions": [],
+							"links": [],
+							"keyword_counts": {},
+							"depth": 0
+						}
+					],
+					"errors": [
+						{
+							"url": "http://example.com/loop",
+							"error": "redirect loop after 1 redirect(s)",
+							"reason": "redirect_loop",
+							"redirects": [
+								{"url": "http://example.com/loop", "status_code": 302, "location": "/loop"}
+							],
+							"depth": 0
+						}
+					]
+				}`,
+		},
+		{
+			name: "returns fetch attempts, bytes read and content types of results and errors",
+			requestBody: `
+				{
+					"urls": ["http://example.com", "http://example.com/down", "http://example.com/image"],
+					"keywords": []
+				}`,
+			mockCrawlService: &mockCrawlService{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					return []services.SuccessCrawlResult{
+						{
+							URL:              "http://example.com",
+							Attempts:         2,
+							AttemptErrors:    []string{"unexpected status code: 429"},
+							BytesRead:        1024,
+							Charset:          "windows-1252",
+							MetaDescriptions: []string{},
+							Links:            []services.Link{},
+							KeywordCounts:    map[string]int{},
+						},
+					}, []services.ErrorCrawlResult{
+						{
+							URL:           "http://example.com/down",
+							Error:         "unexpected status code: 503 (after 2 attempts)",
+							Attempts:      2,
+							AttemptErrors: []string{"unexpected status code: 503", "unexpected status code: 503"},
+						},
+						{
+							URL:         "http://example.com/image",
+							Error:       "unsupported content type: image/png",
+							Reason:      services.ErrorReasonUnsupportedContentType,
+							BytesRead:   512,
+							ContentType: "image/png",
+						},
+					}, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"results": [
+						{
+							"url": "http://example.com",
+							"attempts": 2,
+							"attempt_errors": ["unexpected status code: 429"],
+							"bytes_read": 1024,
+							"charset": "windows-1252",
+							"title": "",
+							"meta_descriptions": [],
+							"links": [],
+							"keyword_counts": {},
+							"depth": 0
+						}
+					],
+					"errors": [
+						{
+							"url": "http://example.com/down",
+							"error": "unexpected status code: 503 (after 2 attempts)",
+							"attempts": 2,
+							"attempt_errors": ["unexpected status code: 503", "unexpected status code: 503"],
+							"depth": 0
+						},
+						{
+							"url": "http://example.com/image",
+							"error": "unsupported content type: image/png",
+							"reason": "unsupported_content_type",
+							"bytes_read": 512,
+							"content_type": "image/png",
+							"depth": 0
+						}
+					]
+				}`,
+		},
+		{
+			name: "disables redirects when follow_redirects is false",
+			requestBody: `
+				{
+					"urls": ["http://example.com"],
+					"keywords": ["example"],
+					"follow_redirects": false
+				}`,
+			mockCrawlService: &mockCrawlService{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					require.Equal(t, extractor.RedirectPolicy{NoFollow: true, MaxRedirects: 10}, opts.Redirects)
+					return []services.SuccessCrawlResult{}, nil, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"results": []
+				}`,
+		},
+		{
+			name: "passes the user agent and header overrides to crawl service",
+			requestBody: `
+				{
+					"urls": ["http://example.com"],
+					"keywords": ["example"],
+					"user_agent": "custom/1.0",
+					"headers": {"Accept-Language": "fr"}
+				}`,
+			mockCrawlService: &mockCrawlService{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					require.Equal(t, "custom/1.0", opts.UserAgent)
+					require.Equal(t, map[string]string{"Accept-Language": "fr"}, opts.Headers)
+					return []services.SuccessCrawlResult{}, nil, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"results": []
+				}`,
+		},
+		{
+			name: "passes keyword options to crawl service and returns keyword results",
+			requestBody: `
+				{
+					"urls": ["http://example.com"],
+					"keywords": ["cat", "#\\d+"],
+					"keyword_options": {
+						"cat": {"mode": "whole_word", "case_insensitive": true, "fold_diacritics": true},
+						"#\\d+": {"mode": "regex"}
+					}
+				}`,
+			mockCrawlService: &mockCrawlService{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					require.Equal(t, map[string]extractor.KeywordOptions{
+						"cat":  {Mode: extractor.KeywordModeWholeWord, CaseInsensitive: true, FoldDiacritics: true},
+						`#\d+`: {Mode: extractor.KeywordModeRegex},
+					}, opts.KeywordOptions)
+
+					return []services.SuccessCrawlResult{
+						{
+							URL:              "http://example.com",
+							MetaDescriptions: []string{},
+							Links:            []services.Link{},
+							KeywordCounts:    map[string]int{"cat": 2, `#\d+`: 1},
+							KeywordResults: map[string]services.KeywordResult{
+								"cat": {
+									Count:           2,
+									Regions:         services.KeywordRegions{Title: 1, H1: 1, Body: 2, AnchorText: 1, URLPath: 1},
+									Density:         12.5,
+									Mode:            "whole_word",
+									CaseInsensitive: true,
+									FoldDiacritics:  true,
+								},
+								`#\d+`: {Count: 1, Regions: services.KeywordRegions{Body: 1}, Density: 6.25, Mode: "regex"},
+							},
+							WordCount: 16,
+						},
+					}, nil, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"results": [
+						{
+							"url": "http://example.com",
+							"title": "",
+							"meta_descriptions": [],
+							"links": [],
+							"keyword_counts": {"cat": 2, "#\\d+": 1},
+							"keyword_results": {
+								"cat": {
+									"count": 2,
+									"regions": {"title": 1, "meta_description": 0, "h1": 1, "h2": 0, "h3": 0, "h4": 0, "h5": 0, "h6": 0, "body": 2, "anchor_text": 1, "image_alt": 0, "url_path": 1},
+									"density": 12.5,
+									"mode": "whole_word",
+									"case_insensitive": true,
+									"fold_diacritics": true
+								},
+								"#\\d+": {
+									"count": 1,
+									"regions": {"title": 0, "meta_description": 0, "h1": 0, "h2": 0, "h3": 0, "h4": 0, "h5": 0, "h6": 0, "body": 1, "anchor_text": 0, "image_alt": 0, "url_path": 0},
+									"density": 6.25,
+									"mode": "regex",
+									"case_insensitive": false,
+									"fold_diacritics": false
+								}
+							},
+							"word_count": 16,
+							"depth": 0
+						}
+					]
+				}`,
+		},
+		{
+			name: "passes max keyword matches to crawl service and returns keyword matches",
+			requestBody: `
+				{
+					"urls": ["http://example.com"],
+					"keywords": ["cat"],
+					"max_keyword_matches": 5
+				}`,
+			mockCrawlService: &mockCrawlService{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					require.Equal(t, 5, opts.MaxKeywordMatches)
+
+					return []services.SuccessCrawlResult{
+						{
+							URL:              "http://example.com",
+							MetaDescriptions: []string{},
+							Links:            []services.Link{},
+							KeywordCounts:    map[string]int{"cat": 1},
+							KeywordResults: map[string]services.KeywordResult{
+								"cat": {
+									Count:   1,
+									Regions: services.KeywordRegions{Body: 1},
+									Density: 20,
+									Mode:    "substring",
+									Matches: []services.KeywordMatch{
+										{Offset: 4, Length: 3, Snippet: "The cat sat down", Path: "div#content > p"},
+									},
+								},
+							},
+							WordCount: 5,
+						},
+					}, nil, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"results": [
+						{
+							"url": "http://example.com",
+							"title": "",
+							"meta_descriptions": [],
+							"links": [],
+							"keyword_counts": {"cat": 1},
+							"keyword_results": {
+								"cat": {
+									"count": 1,
+									"regions": {"title": 0, "meta_description": 0, "h1": 0, "h2": 0, "h3": 0, "h4": 0, "h5": 0, "h6": 0, "body": 1, "anchor_text": 0, "image_alt": 0, "url_path": 0},
+									"density": 20,
+									"mode": "substring",
+									"case_insensitive": false,
+									"fold_diacritics": false,
+									"matches": [
+										{"offset": 4, "length": 3, "snippet": "The cat sat down", "path": "div#content > p"}
+									]
+								}
+							},
+							"word_count": 5,
+							"depth": 0
 						}
 					]
 				}`,
 		},
+		{
+			name: "passes the language to crawl service and returns the language of stemmed keywords",
+			requestBody: `
+				{
+					"urls": ["http://example.com"],
+					"keywords": ["chanteuse"],
+					"keyword_options": {"chanteuse": {"mode": "stemmed"}},
+					"language": "fr"
+				}`,
+			mockCrawlService: &mockCrawlService{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					require.Equal(t, "fr", opts.Language)
+					require.Equal(t, map[string]extractor.KeywordOptions{
+						"chanteuse": {Mode: extractor.KeywordModeStemmed},
+					}, opts.KeywordOptions)
+
+					return []services.SuccessCrawlResult{
+						{
+							URL:              "http://example.com",
+							MetaDescriptions: []string{},
+							Links:            []services.Link{},
+							KeywordCounts:    map[string]int{"chanteuse": 2},
+							KeywordResults: map[string]services.KeywordResult{
+								"chanteuse": {
+									Count:           2,
+									Regions:         services.KeywordRegions{Body: 2},
+									Density:         25,
+									Mode:            "stemmed",
+									CaseInsensitive: true,
+									Language:        "fr",
+								},
+							},
+							WordCount: 8,
+						},
+					}, nil, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"results": [
+						{
+							"url": "http://example.com",
+							"title": "",
+							"meta_descriptions": [],
+							"links": [],
+							"keyword_counts": {"chanteuse": 2},
+							"keyword_results": {
+								"chanteuse": {
+									"count": 2,
+									"regions": {"title": 0, "meta_description": 0, "h1": 0, "h2": 0, "h3": 0, "h4": 0, "h5": 0, "h6": 0, "body": 2, "anchor_text": 0, "image_alt": 0, "url_path": 0},
+									"density": 25,
+									"mode": "stemmed",
+									"case_insensitive": true,
+									"fold_diacritics": false,
+									"language": "fr"
+								}
+							},
+							"word_count": 8,
+							"depth": 0
+						}
+					]
+				}`,
+		},
+		{
+			name: "returns 400 when the language isn't supported",
+			requestBody: `
+				{
+					"urls": ["http://example.com"],
+					"keywords": ["cat"],
+					"language": "ja"
+				}`,
+			mockCrawlService:   &mockCrawlService{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: `
+				{
+					"error": "<<PRESENCE>>"
+				}`,
+		},
+		{
+			name: "returns 400 when max keyword matches is out of range",
+			requestBody: `
+				{
+					"urls": ["http://example.com"],
+					"keywords": ["cat"],
+					"max_keyword_matches": 101
+				}`,
+			mockCrawlService:   &mockCrawlService{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: `
+				{
+					"error": "<<PRESENCE>>"
+				}`,
+		},
+		{
+			name: "returns 400 when keyword options are for an unknown keyword",
+			requestBody: `
+				{
+					"urls": ["http://example.com"],
+					"keywords": ["cat"],
+					"keyword_options": {"dog": {"mode": "whole_word"}}
+				}`,
+			mockCrawlService:   &mockCrawlService{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: `
+				{
+					"error": "keyword_options has options for \"dog\" which isn't one of the keywords"
+				}`,
+		},
+		{
+			name: "returns 400 when a regular expression is invalid",
+			requestBody: `
+				{
+					"urls": ["http://example.com"],
+					"keywords": ["(cat"],
+					"keyword_options": {"(cat": {"mode": "regex"}}
+				}`,
+			mockCrawlService:   &mockCrawlService{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: `
+				{
+					"error": "keyword \"(cat\": invalid keyword: error parsing regexp: missing closing ): ` + "`(cat`" + `"
+				}`,
+		},
+		{
+			name: "returns 400 when cache mode is unknown",
+			requestBody: `
+				{
+					"urls": ["https://example.com"],
+					"keywords": ["example"],
+					"cache": {"mode": "stale"}
+				}`,
+			mockCrawlService:   &mockCrawlService{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: `
+				{
+					"error": "<<PRESENCE>>"
+				}`,
+		},
+		{
+			name: "returns link checks and their summary when checking links",
+			requestBody: `
+				{
+					"urls": ["https://example.com"],
+					"keywords": [],
+					"check_links": true
+				}`,
+			mockCrawlService: &mockCrawlService{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					require.True(t, opts.CheckLinks)
+
+					return []services.SuccessCrawlResult{
+						{
+							URL:              "https://example.com",
+							MetaDescriptions: []string{},
+							Links: []services.Link{
+								{URL: "https://example.com/old", Rel: []string{}, Class: "internal"},
+								{URL: "https://example.com/gone", Rel: []string{}, Class: "internal"},
+							},
+							KeywordCounts: map[string]int{},
+							LinkChecks: []services.LinkCheck{
+								{URL: "https://example.com/old", Method: "HEAD", StatusCode: 301, RedirectURL: "https://example.com/new", Latency: 12 * time.Millisecond},
+								{URL: "https://example.com/gone", Method: "GET", StatusCode: 404, Latency: 30 * time.Millisecond, Broken: true},
+							},
+						},
+					}, nil, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"results": [
+						{
+							"url": "https://example.com",
+							"title": "",
+							"meta_descriptions": [],
+							"links": [
+								{"url": "https://example.com/old", "text": "", "rel": [], "class": "internal"},
+								{"url": "https://example.com/gone", "text": "", "rel": [], "class": "internal"}
+							],
+							"keyword_counts": {},
+							"depth": 0,
+							"link_checks": [
+								{"url": "https://example.com/old", "method": "HEAD", "status_code": 301, "redirect_url": "https://example.com/new", "latency_ms": 12, "broken": false},
+								{"url": "https://example.com/gone", "method": "GET", "status_code": 404, "latency_ms": 30, "broken": true}
+							]
+						}
+					],
+					"link_summary": {
+						"checked": 2,
+						"ok": 0,
+						"redirected": 1,
+						"broken": 1,
+						"error_classes": {},
+						"broken_links": [
+							{"url": "https://example.com/gone", "status_code": 404, "found_on": ["https://example.com"]}
+						]
+					}
+				}`,
+		},
+		{
+			name: "returns 400 when max depth is out of range",
+			requestBody: `
+				{
+					"urls": ["https://example.com"],
+					"keywords": ["example"],
+					"recursive": true,
+					"max_depth": 0
+				}`,
+			mockCrawlService:   &mockCrawlService{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: `
+				{
+					"error": "<<PRESENCE>>"
+				}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -251,3 +897,105 @@ func TestCrawlHandler_Crawl(t *testing.T) {
 		})
 	}
 }
+
+func TestCrawlHandler_CrawlStream(t *testing.T) {
+	// streamingCrawlService reports one success and one error result through the callbacks
+	streamingCrawlService := &mockCrawlService{
+		crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+			success := services.SuccessCrawlResult{
+				URL:              "https://example.com",
+				Title:            "Title",
+				MetaDescriptions: []string{},
+				Links:            []services.Link{},
+				KeywordCounts:    map[string]int{"example": 1},
+			}
+			failure := services.ErrorCrawlResult{
+				URL:   "https://example.com/404",
+				Error: "failed to extract data",
+			}
+			opts.OnSuccess(success)
+			opts.OnError(failure)
+
+			return []services.SuccessCrawlResult{success}, []services.ErrorCrawlResult{failure}, nil
+		},
+	}
+
+	tests := []struct {
+		name                 string
+		accept               string
+		mockCrawlService     *mockCrawlService
+		expectedContentType  string
+		expectedResponseBody string
+	}{
+		{
+			name:                "streams results as ndjson",
+			accept:              "application/x-ndjson",
+			mockCrawlService:    streamingCrawlService,
+			expectedContentType: "application/x-ndjson",
+			expectedResponseBody: `{"type":"result","data":{"url":"https://example.com","title":"Title","meta_descriptions":[],"links":[],"keyword_counts":{"example":1},"depth":0}}
+{"type":"error","data":{"url":"https://example.com/404","error":"failed to extract data","depth":0}}
+{"type":"summary","data":{"succeeded":1,"failed":1}}
+`,
+		},
+		{
+			name:                "streams results as server-sent events",
+			accept:              "text/event-stream",
+			mockCrawlService:    streamingCrawlService,
+			expectedContentType: "text/event-stream",
+			expectedResponseBody: `event: result
+data: {"url":"https://example.com","title":"Title","meta_descriptions":[],"links":[],"keyword_counts":{"example":1},"depth":0}
+
+event: error
+data: {"url":"https://example.com/404","error":"failed to extract data","depth":0}
+
+event: summary
+data: {"succeeded":1,"failed":1}
+
+`,
+		},
+		{
+			name:   "reports crawl service errors in the summary",
+			accept: "text/html, application/x-ndjson;q=0.9",
+			mockCrawlService: &mockCrawlService{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					return nil, nil, fmt.Errorf("error")
+				},
+			},
+			expectedContentType: "application/x-ndjson",
+			expectedResponseBody: `{"type":"summary","data":{"succeeded":0,"failed":0,"error":"error"}}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// initialise router with openapi spec
+			openapiSpec, err := openapi.FS.ReadFile(openapi.OpenAPISpecFilename)
+			require.NoError(t, err)
+
+			loader := openapi3.NewLoader()
+			doc, err := loader.LoadFromData(openapiSpec)
+			require.NoError(t, err)
+
+			router := chi.NewRouter()
+			router.Use(middlewares.OpenAPIValidatorMiddleware(doc))
+
+			h := handlers.NewCrawlHandler(tt.mockCrawlService)
+			router.Post("/crawl", h.Crawl)
+
+			// create request
+			r := httptest.NewRequest(http.MethodPost, "/crawl", strings.NewReader(`{"urls": ["https://example.com"], "keywords": ["example"]}`))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Accept", tt.accept)
+
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			require.True(t, w.Flushed)
+			require.Equal(t, tt.expectedResponseBody, w.Body.String())
+		})
+	}
+}
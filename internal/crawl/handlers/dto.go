@@ -1,61 +1,489 @@
 package handlers
 
-import "github.com/jponc/domain-crawler/internal/crawl/services"
+import (
+	"fmt"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/crawl/services"
+	"github.com/jponc/domain-crawler/internal/extractor"
+)
 
 // Requsts
 
 type CrawlRequest struct {
-	URLs     []string `json:"urls"`
-	Keywords []string `json:"keywords"`
+	URLs      []string     `json:"urls"`
+	Keywords  []string     `json:"keywords"`
+	Recursive bool         `json:"recursive"`
+	MaxDepth  int          `json:"max_depth"`
+	MaxPages  int          `json:"max_pages"`
+	Cache     *CachePolicy `json:"cache,omitempty"`
+	// StripTrackingParams removes analytics query parameters such as utm_source from the returned links
+	StripTrackingParams bool `json:"strip_tracking_params"`
+	// CheckLinks checks every link found on a page and reports broken ones
+	CheckLinks bool `json:"check_links"`
+	// FollowRedirects defaults to true, pages answering with a redirect are reported as errors when it is false
+	FollowRedirects *bool `json:"follow_redirects,omitempty"`
+	// MaxRedirects is the longest redirect chain followed, it defaults to 10
+	MaxRedirects int `json:"max_redirects"`
+	// IgnoreRobots crawls URLs regardless of the robots.txt of their host, e.g. for sites we own
+	IgnoreRobots bool `json:"ignore_robots"`
+	// UserAgent and Headers replace the defaults sent when fetching pages
+	UserAgent string            `json:"user_agent,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	// KeywordOptions holds how keywords are matched, keywords without options are matched as case-sensitive substrings
+	KeywordOptions map[string]KeywordOptions `json:"keyword_options,omitempty"`
+	// MaxKeywordMatches is the number of matches of every keyword returned with their snippet and position
+	MaxKeywordMatches int `json:"max_keyword_matches"`
+	// Language is the ISO 639-1 code of the language stemmed keywords are analyzed in, detected from every page when empty
+	Language string `json:"language,omitempty"`
+}
+
+// KeywordOptions controls how a single keyword is matched, Mode is one of substring, whole_word, regex or stemmed
+type KeywordOptions struct {
+	Mode            string `json:"mode"`
+	CaseInsensitive bool   `json:"case_insensitive"`
+	FoldDiacritics  bool   `json:"fold_diacritics"`
+}
+
+// CachePolicy controls how cached documents are used by a single request
+type CachePolicy struct {
+	Mode          string `json:"mode"`
+	MaxAgeSeconds *int   `json:"max_age_seconds,omitempty"`
 }
 
 // Responses
 
 type CrawlResponse struct {
-	Results []SuccessResult `json:"results"`
-	Errors  []ErrorResult   `json:"errors,omitempty"`
+	Results     []SuccessResult   `json:"results"`
+	Errors      []ErrorResult     `json:"errors,omitempty"`
+	LinkSummary *LinkCheckSummary `json:"link_summary,omitempty"`
+}
+
+// StreamEvent is written for every result when streaming a crawl, Type is one of result, error or summary
+type StreamEvent struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+type JobResponse struct {
+	ID              string      `json:"id"`
+	State           string      `json:"state"`
+	Progress        JobProgress `json:"progress"`
+	Error           string      `json:"error,omitempty"`
+	UnprocessedURLs []string    `json:"unprocessed_urls,omitempty"`
+	CreatedAt       time.Time   `json:"created_at"`
+	StartedAt       *time.Time  `json:"started_at,omitempty"`
+	FinishedAt      *time.Time  `json:"finished_at,omitempty"`
+}
+
+type JobResultsResponse struct {
+	ID          string            `json:"id"`
+	State       string            `json:"state"`
+	Results     []SuccessResult   `json:"results"`
+	Errors      []ErrorResult     `json:"errors,omitempty"`
+	LinkSummary *LinkCheckSummary `json:"link_summary,omitempty"`
 }
 
 // Types
 
 type SuccessResult struct {
 	URL              string         `json:"url"`
+	FinalURL         string         `json:"final_url,omitempty"`
+	Redirects        []Redirect     `json:"redirects,omitempty"`
+	HTTPSDowngrade   bool           `json:"https_downgrade,omitempty"`
+	Attempts         int            `json:"attempts,omitempty"`
+	AttemptErrors    []string       `json:"attempt_errors,omitempty"`
+	BytesRead        int64          `json:"bytes_read,omitempty"`
+	Charset          string         `json:"charset,omitempty"`
 	Title            string         `json:"title"`
 	MetaDescriptions []string       `json:"meta_descriptions"`
-	Links            []string       `json:"links"`
+	Links            []Link         `json:"links"`
 	KeywordCounts    map[string]int `json:"keyword_counts"`
+	// KeywordResults echoes how every keyword was matched along with its count
+	KeywordResults  map[string]KeywordResult `json:"keyword_results,omitempty"`
+	WordCount       int                      `json:"word_count,omitempty"`
+	Source          string                   `json:"source,omitempty"`
+	CacheAgeSeconds *int64                   `json:"cache_age_seconds,omitempty"`
+	Depth           int                      `json:"depth"`
+	DiscoveredFrom  string                   `json:"discovered_from,omitempty"`
+	LinkChecks      []LinkCheck              `json:"link_checks,omitempty"`
+}
+
+type KeywordResult struct {
+	Count           int            `json:"count"`
+	Regions         KeywordRegions `json:"regions"`
+	Density         float64        `json:"density"`
+	Mode            string         `json:"mode"`
+	CaseInsensitive bool           `json:"case_insensitive"`
+	FoldDiacritics  bool           `json:"fold_diacritics"`
+	Language        string         `json:"language,omitempty"`
+	Matches         []KeywordMatch `json:"matches,omitempty"`
+	Error           string         `json:"error,omitempty"`
+}
+
+type KeywordMatch struct {
+	Offset  int    `json:"offset"`
+	Length  int    `json:"length"`
+	Snippet string `json:"snippet"`
+	Path    string `json:"path"`
+}
+
+type KeywordRegions struct {
+	Title           int `json:"title"`
+	MetaDescription int `json:"meta_description"`
+	H1              int `json:"h1"`
+	H2              int `json:"h2"`
+	H3              int `json:"h3"`
+	H4              int `json:"h4"`
+	H5              int `json:"h5"`
+	H6              int `json:"h6"`
+	Body            int `json:"body"`
+	AnchorText      int `json:"anchor_text"`
+	ImageAlt        int `json:"image_alt"`
+	URLPath         int `json:"url_path"`
+}
+
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+	Downgrade  bool   `json:"downgrade,omitempty"`
+}
+
+type Link struct {
+	URL   string   `json:"url"`
+	Text  string   `json:"text"`
+	Rel   []string `json:"rel"`
+	Class string   `json:"class"`
+}
+
+type LinkCheck struct {
+	URL         string `json:"url"`
+	Method      string `json:"method"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	RedirectURL string `json:"redirect_url,omitempty"`
+	LatencyMS   int64  `json:"latency_ms"`
+	ErrorClass  string `json:"error_class,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Broken      bool   `json:"broken"`
+}
+
+type LinkCheckSummary struct {
+	Checked      int            `json:"checked"`
+	OK           int            `json:"ok"`
+	Redirected   int            `json:"redirected"`
+	Broken       int            `json:"broken"`
+	ErrorClasses map[string]int `json:"error_classes"`
+	BrokenLinks  []BrokenLink   `json:"broken_links"`
+}
+
+type BrokenLink struct {
+	URL        string   `json:"url"`
+	StatusCode int      `json:"status_code,omitempty"`
+	ErrorClass string   `json:"error_class,omitempty"`
+	FoundOn    []string `json:"found_on"`
 }
 
 type ErrorResult struct {
-	URL   string `json:"url"`
-	Error string `json:"error"`
+	URL            string     `json:"url"`
+	Error          string     `json:"error"`
+	Reason         string     `json:"reason,omitempty"`
+	Redirects      []Redirect `json:"redirects,omitempty"`
+	Attempts       int        `json:"attempts,omitempty"`
+	AttemptErrors  []string   `json:"attempt_errors,omitempty"`
+	BytesRead      int64      `json:"bytes_read,omitempty"`
+	ContentType    string     `json:"content_type,omitempty"`
+	Depth          int        `json:"depth"`
+	DiscoveredFrom string     `json:"discovered_from,omitempty"`
+}
+
+// CrawlSummary is the last event written when streaming a crawl
+type CrawlSummary struct {
+	Succeeded   int               `json:"succeeded"`
+	Failed      int               `json:"failed"`
+	Error       string            `json:"error,omitempty"`
+	LinkSummary *LinkCheckSummary `json:"link_summary,omitempty"`
+}
+
+type JobProgress struct {
+	Scheduled int `json:"scheduled"`
+	Completed int `json:"completed"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// DTO to Domain converters
+
+func convertCrawlRequestToCrawlOptions(req CrawlRequest) services.CrawlOptions {
+	opts := services.CrawlOptions{
+		Recursive:           req.Recursive,
+		MaxDepth:            req.MaxDepth,
+		MaxPages:            req.MaxPages,
+		StripTrackingParams: req.StripTrackingParams,
+		CheckLinks:          req.CheckLinks,
+		IgnoreRobots:        req.IgnoreRobots,
+		UserAgent:           req.UserAgent,
+		Headers:             req.Headers,
+		MaxKeywordMatches:   req.MaxKeywordMatches,
+		Language:            req.Language,
+		Redirects: extractor.RedirectPolicy{
+			NoFollow:     req.FollowRedirects != nil && !*req.FollowRedirects,
+			MaxRedirects: req.MaxRedirects,
+		},
+	}
+
+	if len(req.KeywordOptions) > 0 {
+		opts.KeywordOptions = make(map[string]extractor.KeywordOptions, len(req.KeywordOptions))
+		for keyword, keywordOptions := range req.KeywordOptions {
+			opts.KeywordOptions[keyword] = convertKeywordOptions(keywordOptions)
+		}
+	}
+
+	if req.Cache != nil {
+		opts.Cache.Mode = extractor.CacheMode(req.Cache.Mode)
+		if req.Cache.MaxAgeSeconds != nil {
+			maxAge := time.Duration(*req.Cache.MaxAgeSeconds) * time.Second
+			opts.Cache.MaxAge = &maxAge
+		}
+	}
+
+	return opts
+}
+
+func convertKeywordOptions(keywordOptions KeywordOptions) extractor.KeywordOptions {
+	return extractor.KeywordOptions{
+		Mode:            extractor.KeywordMode(keywordOptions.Mode),
+		CaseInsensitive: keywordOptions.CaseInsensitive,
+		FoldDiacritics:  keywordOptions.FoldDiacritics,
+	}
+}
+
+// validateKeywordOptions checks the options only apply to requested keywords and that they can be matched,
+// e.g. that regular expressions compile
+func validateKeywordOptions(req CrawlRequest) error {
+	keywords := make(map[string]bool, len(req.Keywords))
+	for _, keyword := range req.Keywords {
+		keywords[keyword] = true
+	}
+
+	for keyword, keywordOptions := range req.KeywordOptions {
+		if !keywords[keyword] {
+			return fmt.Errorf("keyword_options has options for %q which isn't one of the keywords", keyword)
+		}
+
+		err := extractor.ValidateKeyword(keyword, convertKeywordOptions(keywordOptions))
+		if err != nil {
+			return fmt.Errorf("keyword %q: %w", keyword, err)
+		}
+	}
+
+	return nil
 }
 
 // Domain to DTO converters
 
+func convertSuccessCrawlResultToSuccessResult(crawlResult services.SuccessCrawlResult) SuccessResult {
+	result := SuccessResult{
+		URL:              crawlResult.URL,
+		FinalURL:         crawlResult.FinalURL,
+		Redirects:        convertRedirectsToRedirects(crawlResult.Redirects),
+		HTTPSDowngrade:   crawlResult.HTTPSDowngrade,
+		Attempts:         crawlResult.Attempts,
+		AttemptErrors:    crawlResult.AttemptErrors,
+		BytesRead:        crawlResult.BytesRead,
+		Charset:          crawlResult.Charset,
+		Title:            crawlResult.Title,
+		MetaDescriptions: crawlResult.MetaDescriptions,
+		Links:            convertLinksToLinks(crawlResult.Links),
+		KeywordCounts:    crawlResult.KeywordCounts,
+		KeywordResults:   convertKeywordResultsToKeywordResults(crawlResult.KeywordResults),
+		WordCount:        crawlResult.WordCount,
+		Source:           crawlResult.Source,
+		Depth:            crawlResult.Depth,
+		DiscoveredFrom:   crawlResult.DiscoveredFrom,
+	}
+
+	if crawlResult.LinkChecks != nil {
+		result.LinkChecks = convertLinkChecksToLinkChecks(crawlResult.LinkChecks)
+	}
+
+	if crawlResult.CacheAge != nil {
+		cacheAgeSeconds := int64(crawlResult.CacheAge.Seconds())
+		result.CacheAgeSeconds = &cacheAgeSeconds
+	}
+
+	return result
+}
+
+// convertKeywordResultsToKeywordResults returns nil when there were no keywords so they are omitted
+func convertKeywordResultsToKeywordResults(keywordResults map[string]services.KeywordResult) map[string]KeywordResult {
+	if len(keywordResults) == 0 {
+		return nil
+	}
+
+	converted := make(map[string]KeywordResult, len(keywordResults))
+	for keyword, keywordResult := range keywordResults {
+		converted[keyword] = KeywordResult{
+			Count:           keywordResult.Count,
+			Regions:         KeywordRegions(keywordResult.Regions),
+			Density:         keywordResult.Density,
+			Mode:            keywordResult.Mode,
+			CaseInsensitive: keywordResult.CaseInsensitive,
+			FoldDiacritics:  keywordResult.FoldDiacritics,
+			Language:        keywordResult.Language,
+			Matches:         convertKeywordMatchesToKeywordMatches(keywordResult.Matches),
+			Error:           keywordResult.Error,
+		}
+	}
+	return converted
+}
+
+// convertKeywordMatchesToKeywordMatches returns nil when matches weren't asked for so they are omitted
+func convertKeywordMatchesToKeywordMatches(matches []services.KeywordMatch) []KeywordMatch {
+	if matches == nil {
+		return nil
+	}
+
+	converted := make([]KeywordMatch, 0, len(matches))
+	for _, match := range matches {
+		converted = append(converted, KeywordMatch(match))
+	}
+	return converted
+}
+
+func convertRedirectsToRedirects(redirects []services.Redirect) []Redirect {
+	converted := make([]Redirect, 0, len(redirects))
+	for _, redirect := range redirects {
+		converted = append(converted, Redirect{
+			URL:        redirect.URL,
+			StatusCode: redirect.StatusCode,
+			Location:   redirect.Location,
+			Downgrade:  redirect.Downgrade,
+		})
+	}
+	return converted
+}
+
+func convertLinksToLinks(links []services.Link) []Link {
+	converted := make([]Link, 0, len(links))
+	for _, link := range links {
+		converted = append(converted, Link{
+			URL:   link.URL,
+			Text:  link.Text,
+			Rel:   link.Rel,
+			Class: link.Class,
+		})
+	}
+	return converted
+}
+
+func convertLinkChecksToLinkChecks(linkChecks []services.LinkCheck) []LinkCheck {
+	converted := make([]LinkCheck, 0, len(linkChecks))
+	for _, linkCheck := range linkChecks {
+		converted = append(converted, LinkCheck{
+			URL:         linkCheck.URL,
+			Method:      linkCheck.Method,
+			StatusCode:  linkCheck.StatusCode,
+			RedirectURL: linkCheck.RedirectURL,
+			LatencyMS:   linkCheck.Latency.Milliseconds(),
+			ErrorClass:  linkCheck.ErrorClass,
+			Error:       linkCheck.Error,
+			Broken:      linkCheck.Broken,
+		})
+	}
+	return converted
+}
+
+// convertLinkCheckSummary summarizes the link checks of the results, it returns nil when links weren't checked
+func convertLinkCheckSummary(crawlResults []services.SuccessCrawlResult) *LinkCheckSummary {
+	summary, ok := services.SummarizeLinkChecks(crawlResults)
+	if !ok {
+		return nil
+	}
+
+	brokenLinks := make([]BrokenLink, 0, len(summary.BrokenLinks))
+	for _, brokenLink := range summary.BrokenLinks {
+		brokenLinks = append(brokenLinks, BrokenLink{
+			URL:        brokenLink.URL,
+			StatusCode: brokenLink.StatusCode,
+			ErrorClass: brokenLink.ErrorClass,
+			FoundOn:    brokenLink.FoundOn,
+		})
+	}
+
+	return &LinkCheckSummary{
+		Checked:      summary.Checked,
+		OK:           summary.OK,
+		Redirected:   summary.Redirected,
+		Broken:       summary.Broken,
+		ErrorClasses: summary.ErrorClasses,
+		BrokenLinks:  brokenLinks,
+	}
+}
+
 func convertSuccessCrawlResultsToSuccessResults(crawlResults []services.SuccessCrawlResult) []SuccessResult {
 	results := make([]SuccessResult, 0, len(crawlResults))
 	for _, crawlResult := range crawlResults {
-		result := SuccessResult{
-			URL:              crawlResult.URL,
-			Title:            crawlResult.Title,
-			MetaDescriptions: crawlResult.MetaDescriptions,
-			Links:            crawlResult.Links,
-			KeywordCounts:    crawlResult.KeywordCounts,
-		}
-		results = append(results, result)
+		results = append(results, convertSuccessCrawlResultToSuccessResult(crawlResult))
 	}
 	return results
 }
 
+func convertErrorCrawlResultToErrorResult(crawlResult services.ErrorCrawlResult) ErrorResult {
+	return ErrorResult{
+		URL:            crawlResult.URL,
+		Error:          crawlResult.Error,
+		Reason:         string(crawlResult.Reason),
+		Redirects:      convertRedirectsToRedirects(crawlResult.Redirects),
+		Attempts:       crawlResult.Attempts,
+		AttemptErrors:  crawlResult.AttemptErrors,
+		BytesRead:      crawlResult.BytesRead,
+		ContentType:    crawlResult.ContentType,
+		Depth:          crawlResult.Depth,
+		DiscoveredFrom: crawlResult.DiscoveredFrom,
+	}
+}
+
 func convertErrorCrawlResultsToErrorResults(crawlResults []services.ErrorCrawlResult) []ErrorResult {
 	results := make([]ErrorResult, 0, len(crawlResults))
 	for _, crawlResult := range crawlResults {
-		result := ErrorResult{
-			URL:   crawlResult.URL,
-			Error: crawlResult.Error,
-		}
-		results = append(results, result)
+		results = append(results, convertErrorCrawlResultToErrorResult(crawlResult))
 	}
 	return results
 }
+
+func convertJobToJobResponse(job services.Job) JobResponse {
+	resp := JobResponse{
+		ID:    job.ID,
+		State: string(job.State),
+		Progress: JobProgress{
+			Scheduled: job.Progress.Scheduled,
+			Completed: job.Progress.Completed,
+			Succeeded: job.Progress.Succeeded,
+			Failed:    job.Progress.Failed,
+		},
+		Error:           job.Error,
+		UnprocessedURLs: job.UnprocessedURLs,
+		CreatedAt:       job.CreatedAt,
+	}
+
+	if !job.StartedAt.IsZero() {
+		resp.StartedAt = &job.StartedAt
+	}
+
+	if !job.FinishedAt.IsZero() {
+		resp.FinishedAt = &job.FinishedAt
+	}
+
+	return resp
+}
+
+func convertJobResultsToJobResultsResponse(jobResults services.JobResults) JobResultsResponse {
+	return JobResultsResponse{
+		ID:          jobResults.Job.ID,
+		State:       string(jobResults.Job.State),
+		Results:     convertSuccessCrawlResultsToSuccessResults(jobResults.SuccessCrawlResults),
+		Errors:      convertErrorCrawlResultsToErrorResults(jobResults.ErrorCrawlResults),
+		LinkSummary: convertLinkCheckSummary(jobResults.SuccessCrawlResults),
+	}
+}
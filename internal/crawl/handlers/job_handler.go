@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jponc/domain-crawler/internal/crawl/services"
+	"github.com/jponc/domain-crawler/internal/errs"
+	"github.com/jponc/domain-crawler/internal/utils"
+)
+
+type jobService interface {
+	Submit(urls []string, keywords []string, opts services.CrawlOptions) (services.Job, error)
+	Get(id string) (services.Job, error)
+	Results(id string) (services.JobResults, error)
+	Cancel(ctx context.Context, id string) (services.Job, error)
+}
+
+type jobHandler struct {
+	jobService jobService
+}
+
+func NewJobHandler(jobService jobService) *jobHandler {
+	h := &jobHandler{
+		jobService: jobService,
+	}
+
+	return h
+}
+
+func (h *jobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var reqBody CrawlRequest
+
+	// Decode request body
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errResp := errs.ErrorResponse{Error: "failed to decode request body"}
+		_ = json.NewEncoder(w).Encode(errResp)
+		return
+	}
+
+	err = validateKeywordOptions(reqBody)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errResp := errs.ErrorResponse{Error: err.Error()}
+		_ = json.NewEncoder(w).Encode(errResp)
+		return
+	}
+
+	// Remove duplicate urls if any
+	uniqueURLs := utils.RemoveDuplicates(reqBody.URLs)
+
+	// Queue the job, it runs in the background
+	job, err := h.jobService.Submit(uniqueURLs, reqBody.Keywords, convertCrawlRequestToCrawlOptions(reqBody))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		errResp := errs.ErrorResponse{Error: err.Error()}
+		_ = json.NewEncoder(w).Encode(errResp)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(convertJobToJobResponse(job))
+}
+
+func (h *jobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobService.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(convertJobToJobResponse(job))
+}
+
+func (h *jobHandler) GetJobResults(w http.ResponseWriter, r *http.Request) {
+	jobResults, err := h.jobService.Results(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(convertJobResultsToJobResultsResponse(jobResults))
+}
+
+func (h *jobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobService.Cancel(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(convertJobToJobResponse(job))
+}
+
+// writeJobError maps job service errors to a response
+func writeJobError(w http.ResponseWriter, err error) {
+	statusCode := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, services.ErrJobFinished):
+		statusCode = http.StatusConflict
+	}
+
+	w.WriteHeader(statusCode)
+	errResp := errs.ErrorResponse{Error: err.Error()}
+	_ = json.NewEncoder(w).Encode(errResp)
+}
@@ -0,0 +1,353 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/jponc/domain-crawler/api/openapi"
+	"github.com/jponc/domain-crawler/internal/crawl/handlers"
+	"github.com/jponc/domain-crawler/internal/crawl/services"
+	"github.com/jponc/domain-crawler/internal/middlewares"
+	"github.com/kinbiko/jsonassert"
+	"github.com/stretchr/testify/require"
+)
+
+// Mocks
+type mockJobService struct {
+	submitFn  func(urls []string, keywords []string, opts services.CrawlOptions) (services.Job, error)
+	getFn     func(id string) (services.Job, error)
+	resultsFn func(id string) (services.JobResults, error)
+	cancelFn  func(ctx context.Context, id string) (services.Job, error)
+}
+
+func (m *mockJobService) Submit(urls []string, keywords []string, opts services.CrawlOptions) (services.Job, error) {
+	if m != nil && m.submitFn != nil {
+		return m.submitFn(urls, keywords, opts)
+	}
+
+	return services.Job{}, nil
+}
+
+func (m *mockJobService) Get(id string) (services.Job, error) {
+	if m != nil && m.getFn != nil {
+		return m.getFn(id)
+	}
+
+	return services.Job{}, services.ErrJobNotFound
+}
+
+func (m *mockJobService) Results(id string) (services.JobResults, error) {
+	if m != nil && m.resultsFn != nil {
+		return m.resultsFn(id)
+	}
+
+	return services.JobResults{}, services.ErrJobNotFound
+}
+
+func (m *mockJobService) Cancel(ctx context.Context, id string) (services.Job, error) {
+	if m != nil && m.cancelFn != nil {
+		return m.cancelFn(ctx, id)
+	}
+
+	return services.Job{}, services.ErrJobNotFound
+}
+
+var (
+	jobCreatedAt  = time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC)
+	jobStartedAt  = time.Date(2024, 9, 1, 10, 0, 1, 0, time.UTC)
+	jobFinishedAt = time.Date(2024, 9, 1, 10, 0, 5, 0, time.UTC)
+)
+
+func TestJobHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		method               string
+		path                 string
+		requestBody          string
+		mockJobService       *mockJobService
+		expectedStatusCode   int
+		expectedLocation     string
+		expectedResponseBody string
+	}{
+		{
+			name:   "returns 202 with the queued job",
+			method: http.MethodPost,
+			path:   "/jobs",
+			requestBody: `
+				{
+					"urls": ["https://example.com", "https://example.com"],
+					"keywords": ["example"]
+				}`,
+			mockJobService: &mockJobService{
+				submitFn: func(urls []string, keywords []string, opts services.CrawlOptions) (services.Job, error) {
+					require.Equal(t, []string{"https://example.com"}, urls)
+					require.Equal(t, []string{"example"}, keywords)
+
+					return services.Job{ID: "job1", State: services.JobStateQueued, CreatedAt: jobCreatedAt}, nil
+				},
+			},
+			expectedStatusCode: http.StatusAccepted,
+			expectedLocation:   "/jobs/job1",
+			expectedResponseBody: `
+				{
+					"id": "job1",
+					"state": "queued",
+					"progress": {"scheduled": 0, "completed": 0, "succeeded": 0, "failed": 0},
+					"created_at": "2024-09-01T10:00:00Z"
+				}`,
+		},
+		{
+			name:               "returns 400 when job request doesn't conform to openapi spec",
+			method:             http.MethodPost,
+			path:               "/jobs",
+			requestBody:        "{}",
+			mockJobService:     &mockJobService{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: `
+				{
+					"error": "request body has an error: doesn't match schema #/components/schemas/CrawlRequest: Error at \"/urls\": property \"urls\" is missing"
+				}`,
+		},
+		{
+			name:   "returns 400 when keyword options can't be matched",
+			method: http.MethodPost,
+			path:   "/jobs",
+			requestBody: `
+				{
+					"urls": ["https://example.com"],
+					"keywords": ["example"],
+					"keyword_options": {"example": {"mode": "regex", "case_insensitive": true}, "other": {}}
+				}`,
+			mockJobService:     &mockJobService{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponseBody: `
+				{
+					"error": "keyword_options has options for \"other\" which isn't one of the keywords"
+				}`,
+		},
+		{
+			name:   "returns 500 when job can't be submitted",
+			method: http.MethodPost,
+			path:   "/jobs",
+			requestBody: `
+				{
+					"urls": ["https://example.com"],
+					"keywords": ["example"]
+				}`,
+			mockJobService: &mockJobService{
+				submitFn: func(urls []string, keywords []string, opts services.CrawlOptions) (services.Job, error) {
+					return services.Job{}, fmt.Errorf("error")
+				},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedResponseBody: `
+				{
+					"error": "error"
+				}`,
+		},
+		{
+			name:   "returns 200 with the job state and progress",
+			method: http.MethodGet,
+			path:   "/jobs/job1",
+			mockJobService: &mockJobService{
+				getFn: func(id string) (services.Job, error) {
+					require.Equal(t, "job1", id)
+
+					return services.Job{
+						ID:         "job1",
+						State:      services.JobStateDone,
+						Progress:   services.JobProgress{Scheduled: 2, Completed: 2, Succeeded: 1, Failed: 1},
+						CreatedAt:  jobCreatedAt,
+						StartedAt:  jobStartedAt,
+						FinishedAt: jobFinishedAt,
+					}, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"id": "job1",
+					"state": "done",
+					"progress": {"scheduled": 2, "completed": 2, "succeeded": 1, "failed": 1},
+					"created_at": "2024-09-01T10:00:00Z",
+					"started_at": "2024-09-01T10:00:01Z",
+					"finished_at": "2024-09-01T10:00:05Z"
+				}`,
+		},
+		{
+			name:               "returns 404 when job doesn't exist",
+			method:             http.MethodGet,
+			path:               "/jobs/unknown",
+			mockJobService:     &mockJobService{},
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponseBody: `
+				{
+					"error": "job not found"
+				}`,
+		},
+		{
+			name:   "returns 200 with the job results",
+			method: http.MethodGet,
+			path:   "/jobs/job1/results",
+			mockJobService: &mockJobService{
+				resultsFn: func(id string) (services.JobResults, error) {
+					require.Equal(t, "job1", id)
+
+					return services.JobResults{
+						Job: services.Job{ID: "job1", State: services.JobStateRunning},
+						SuccessCrawlResults: []services.SuccessCrawlResult{
+							{
+								URL:              "https://example.com",
+								Title:            "Title",
+								MetaDescriptions: []string{},
+								Links:            []services.Link{},
+								KeywordCounts:    map[string]int{"example": 1},
+							},
+						},
+						ErrorCrawlResults: []services.ErrorCrawlResult{
+							{
+								URL:   "https://example.com/404",
+								Error: "failed to extract data",
+							},
+						},
+					}, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"id": "job1",
+					"state": "running",
+					"results": [
+						{
+							"url": "https://example.com",
+							"title": "Title",
+							"meta_descriptions": [],
+							"links": [],
+							"keyword_counts": {"example": 1},
+							"depth": 0
+						}
+					],
+					"errors": [
+						{
+							"url": "https://example.com/404",
+							"error": "failed to extract data",
+							"depth": 0
+						}
+					]
+				}`,
+		},
+		{
+			name:   "returns 200 with the cancelled job",
+			method: http.MethodDelete,
+			path:   "/jobs/job1",
+			mockJobService: &mockJobService{
+				cancelFn: func(ctx context.Context, id string) (services.Job, error) {
+					require.Equal(t, "job1", id)
+
+					return services.Job{
+						ID:              "job1",
+						State:           services.JobStateCancelled,
+						Progress:        services.JobProgress{Scheduled: 2, Completed: 1, Succeeded: 1},
+						UnprocessedURLs: []string{"https://example.com/a"},
+						CreatedAt:       jobCreatedAt,
+						StartedAt:       jobStartedAt,
+						FinishedAt:      jobFinishedAt,
+					}, nil
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseBody: `
+				{
+					"id": "job1",
+					"state": "cancelled",
+					"progress": {"scheduled": 2, "completed": 1, "succeeded": 1, "failed": 0},
+					"unprocessed_urls": ["https://example.com/a"],
+					"created_at": "2024-09-01T10:00:00Z",
+					"started_at": "2024-09-01T10:00:01Z",
+					"finished_at": "2024-09-01T10:00:05Z"
+				}`,
+		},
+		{
+			name:   "returns 409 when cancelling a finished job",
+			method: http.MethodDelete,
+			path:   "/jobs/job1",
+			mockJobService: &mockJobService{
+				cancelFn: func(ctx context.Context, id string) (services.Job, error) {
+					return services.Job{}, services.ErrJobFinished
+				},
+			},
+			expectedStatusCode: http.StatusConflict,
+			expectedResponseBody: `
+				{
+					"error": "job already finished"
+				}`,
+		},
+		{
+			name:               "returns 404 when cancelling a job that doesn't exist",
+			method:             http.MethodDelete,
+			path:               "/jobs/unknown",
+			mockJobService:     &mockJobService{},
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponseBody: `
+				{
+					"error": "job not found"
+				}`,
+		},
+		{
+			name:               "returns 404 when job results don't exist",
+			method:             http.MethodGet,
+			path:               "/jobs/unknown/results",
+			mockJobService:     &mockJobService{},
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponseBody: `
+				{
+					"error": "job not found"
+				}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// initialise router with openapi spec
+			openapiSpec, err := openapi.FS.ReadFile(openapi.OpenAPISpecFilename)
+			require.NoError(t, err)
+
+			loader := openapi3.NewLoader()
+			doc, err := loader.LoadFromData(openapiSpec)
+			require.NoError(t, err)
+
+			oapiValidatorMiddleware := middlewares.OpenAPIValidatorMiddleware(doc)
+			router := chi.NewRouter()
+			router.Use(oapiValidatorMiddleware)
+
+			// initialise handlers
+			h := handlers.NewJobHandler(tt.mockJobService)
+
+			// setup routes
+			router.Post("/jobs", h.CreateJob)
+			router.Get("/jobs/{id}", h.GetJob)
+			router.Get("/jobs/{id}/results", h.GetJobResults)
+			router.Delete("/jobs/{id}", h.CancelJob)
+
+			// create request
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.requestBody))
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			require.Equal(t, tt.expectedStatusCode, w.Code)
+			require.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+			jsonassert.New(t).Assertf(w.Body.String(), "%s", tt.expectedResponseBody)
+		})
+	}
+}
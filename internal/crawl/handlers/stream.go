@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	contentTypeNDJSON      = "application/x-ndjson"
+	contentTypeEventStream = "text/event-stream"
+)
+
+const (
+	streamEventResult  = "result"
+	streamEventError   = "error"
+	streamEventSummary = "summary"
+)
+
+// streamWriter writes crawl events to the response as soon as they are produced,
+// either as newline delimited JSON or as Server-Sent Events
+type streamWriter struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	sse        bool
+}
+
+// negotiateStream returns the streaming content type accepted by the client, if any
+func negotiateStream(r *http.Request) (string, bool) {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case contentTypeNDJSON, contentTypeEventStream:
+			return mediaType, true
+		}
+	}
+
+	return "", false
+}
+
+func newStreamWriter(w http.ResponseWriter, contentType string) *streamWriter {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	s := &streamWriter{
+		w:          w,
+		controller: http.NewResponseController(w),
+		sse:        contentType == contentTypeEventStream,
+	}
+
+	// Send the headers straight away so clients know the crawl started
+	_ = s.controller.Flush()
+
+	return s
+}
+
+func (s *streamWriter) writeEvent(eventType string, data any) {
+	if s.sse {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return
+		}
+
+		_, _ = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", eventType, payload)
+	} else {
+		_ = json.NewEncoder(s.w).Encode(StreamEvent{Type: eventType, Data: data})
+	}
+
+	_ = s.controller.Flush()
+}
@@ -2,79 +2,415 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
 
 	"github.com/jponc/domain-crawler/internal/extractor"
+	"github.com/jponc/domain-crawler/internal/linkchecker"
+	"github.com/jponc/domain-crawler/internal/robots"
+	"github.com/jponc/domain-crawler/internal/urlpolicy"
+	"github.com/jponc/domain-crawler/internal/utils"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/sync/errgroup"
 )
 
+const (
+	defaultMaxDepth = 2
+	defaultMaxPages = 100
+)
+
+// errRobotsDisallowed is returned for URLs the robots.txt of their host disallows
+var errRobotsDisallowed = errors.New("disallowed by robots.txt")
+
 type extractorClient interface {
-	Extract(ctx context.Context, url string, keywords []string) (*extractor.ExtractResult, error)
+	Extract(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error)
+}
+
+type linkChecker interface {
+	Check(ctx context.Context, urls []string) []linkchecker.Result
+}
+
+type robotsChecker interface {
+	Check(ctx context.Context, url string) (robots.Decision, error)
+}
+
+type urlPolicy interface {
+	CheckURL(url string) error
 }
 
 type crawlService struct {
 	extractorClient extractorClient
+	linkChecker     linkChecker
+	robotsChecker   robotsChecker
+	hostScheduler   *hostScheduler
+	urlPolicy       urlPolicy
 	concurrentLimit int
 	logger          zerolog.Logger
 }
 
-func NewCrawlService(extractorClient extractorClient, concurrentLimit int) *crawlService {
+// page is a URL in the crawl frontier
+type page struct {
+	url            string
+	depth          int
+	discoveredFrom string
+}
+
+// NewCrawlService returns a service fetching at most concurrentLimit pages at a time per crawl,
+// within the per-host limits of the hostScheduler.
+func NewCrawlService(extractorClient extractorClient, linkChecker linkChecker, robotsChecker robotsChecker, hostScheduler *hostScheduler, urlPolicy urlPolicy, concurrentLimit int) *crawlService {
 	return &crawlService{
 		extractorClient: extractorClient,
+		linkChecker:     linkChecker,
+		robotsChecker:   robotsChecker,
+		hostScheduler:   hostScheduler,
+		urlPolicy:       urlPolicy,
 		concurrentLimit: concurrentLimit,
 		logger:          log.With().Str("package", "services").Str("service", "CrawlService").Logger(),
 	}
 }
 
-func (s *crawlService) Crawl(ctx context.Context, urls []string, keywords []string) ([]SuccessCrawlResult, []ErrorCrawlResult, error) {
+func (s *crawlService) Crawl(ctx context.Context, urls []string, keywords []string, opts CrawlOptions) ([]SuccessCrawlResult, []ErrorCrawlResult, error) {
 	successCrawlResults := []SuccessCrawlResult{}
 	errorCrawlResults := []ErrorCrawlResult{}
 
-	// Define errgroup
-	eg, egCtx := errgroup.WithContext(ctx)
+	// Only the seed URLs are crawled unless recursive crawling is requested
+	maxDepth := 0
+	maxPages := len(urls)
+	if opts.Recursive {
+		maxDepth = opts.MaxDepth
+		if maxDepth <= 0 {
+			maxDepth = defaultMaxDepth
+		}
 
-	// Set limit for concurrent requests
-	eg.SetLimit(s.concurrentLimit)
+		maxPages = opts.MaxPages
+		if maxPages <= 0 {
+			maxPages = defaultMaxPages
+		}
+	}
 
-	// Iterate over urls and extract data
+	// Seed the frontier
+	visited := map[string]bool{}
+	frontier := []page{}
 	for _, url := range urls {
-		url := url
-		eg.Go(func() error {
-			s.logger.Info().Str("url", url).Msg("Extracting data from URL")
-			result, err := s.extractorClient.Extract(egCtx, url, keywords)
-			// Handle error
-			if err != nil {
-				s.logger.Error().Str("url", url).Msg("Failed to extract data from URL")
-				errorCrawlResults = append(errorCrawlResults, ErrorCrawlResult{
-					URL:   url,
-					Error: err.Error(),
-				})
-				return nil
-			}
-
-			// Handle success result
-			s.logger.Info().Str("url", url).Msg("Successfully extracted data from URL")
-			successCrawlResult := SuccessCrawlResult{
-				URL:              result.URL,
-				Title:            result.Title,
-				MetaDescriptions: result.MetaDescriptions,
-				Links:            result.Links,
-				KeywordCounts:    result.KeywordCounts,
-			}
-			successCrawlResults = append(successCrawlResults, successCrawlResult)
-			return nil
-		})
+		if visited[utils.NormalizeURL(url)] || len(frontier) >= maxPages {
+			continue
+		}
+
+		visited[utils.NormalizeURL(url)] = true
+		frontier = append(frontier, page{url: url})
+		if opts.OnScheduled != nil {
+			opts.OnScheduled(url)
+		}
 	}
+	scheduled := len(frontier)
 
-	// Wait for all requests to finish
-	err := eg.Wait()
-	if err != nil {
-		// This is actually not gonna happen as we are not returning any error from the goroutines
-		// But handling the error just in case
-		return nil, nil, err
+	// Guards the results, the visited set and the next frontier
+	var mu sync.Mutex
+
+	linkChecks := newLinkCheckMemo(s.linkChecker)
+
+	// Pages wait for their host before taking one of the crawl's slots, so busy hosts don't hold slots other hosts could use
+	slots := make(chan struct{}, s.concurrentLimit)
+
+	// Crawl the frontier one depth at a time so every page is reported at its shortest depth
+	for len(frontier) > 0 {
+		nextFrontier := []page{}
+
+		// Define errgroup, concurrent requests are limited by the slots
+		eg, egCtx := errgroup.WithContext(ctx)
+
+		// Iterate over pages and extract data
+		for _, p := range frontier {
+			p := p
+			eg.Go(func() error {
+				result, err := s.extract(egCtx, p, keywords, opts, slots)
+
+				// Link checks run within the link checker's own concurrency budget
+				var pageLinkChecks []LinkCheck
+				if err == nil && opts.CheckLinks {
+					linkURLs := make([]string, 0, len(result.Links))
+					for _, link := range result.Links {
+						linkURLs = append(linkURLs, link.URL)
+					}
+					pageLinkChecks = linkChecks.check(egCtx, linkURLs)
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				// Handle error
+				if err != nil {
+					errorCrawlResult := ErrorCrawlResult{
+						URL:            p.url,
+						Error:          err.Error(),
+						Depth:          p.depth,
+						DiscoveredFrom: p.discoveredFrom,
+					}
+
+					var attemptsErr *extractor.AttemptsError
+					if errors.As(err, &attemptsErr) {
+						errorCrawlResult.Attempts = len(attemptsErr.Errors)
+						errorCrawlResult.AttemptErrors = attemptsErr.Errors
+						errorCrawlResult.BytesRead = attemptsErr.BytesRead
+					}
+
+					// URLs never started or aborted mid fetch are reported as unprocessed
+					var redirectErr *extractor.RedirectError
+					var contentTypeErr *extractor.ContentTypeError
+					if egCtx.Err() != nil && errors.Is(err, egCtx.Err()) {
+						s.logger.Info().Str("url", p.url).Msg("Crawl cancelled, URL left unprocessed")
+						errorCrawlResult.Reason = ErrorReasonCancelled
+					} else if errors.Is(err, urlpolicy.ErrBlocked) {
+						s.logger.Warn().Str("url", p.url).Err(err).Msg("URL blocked by the URL policy, skipping it")
+						errorCrawlResult.Reason = ErrorReasonBlocked
+					} else if errors.Is(err, errRobotsDisallowed) {
+						s.logger.Info().Str("url", p.url).Msg("URL disallowed by robots.txt, skipping it")
+						errorCrawlResult.Reason = ErrorReasonRobotsDisallowed
+					} else if errors.Is(err, extractor.ErrCircuitOpen) {
+						s.logger.Warn().Str("url", p.url).Msg("Circuit of the host is open, skipping URL")
+						errorCrawlResult.Reason = ErrorReasonCircuitOpen
+					} else if errors.Is(err, extractor.ErrBodyTooLarge) {
+						s.logger.Warn().Str("url", p.url).Err(err).Msg("Page is too large, skipping it")
+						errorCrawlResult.Reason = ErrorReasonBodyTooLarge
+					} else if errors.As(err, &contentTypeErr) {
+						s.logger.Info().Str("url", p.url).Str("content_type", contentTypeErr.ContentType).Msg("Page isn't HTML, skipping it")
+						errorCrawlResult.Reason = ErrorReasonUnsupportedContentType
+						errorCrawlResult.ContentType = contentTypeErr.ContentType
+					} else if errors.As(err, &redirectErr) {
+						s.logger.Error().Str("url", p.url).Err(redirectErr).Msg("Failed to follow redirects of URL")
+						errorCrawlResult.Reason = redirectErrorReason(redirectErr)
+						errorCrawlResult.Redirects = convertRedirects(redirectErr.Redirects)
+					} else {
+						s.logger.Error().Str("url", p.url).Msg("Failed to extract data from URL")
+					}
+
+					errorCrawlResults = append(errorCrawlResults, errorCrawlResult)
+					if opts.OnError != nil {
+						opts.OnError(errorCrawlResult)
+					}
+					return nil
+				}
+
+				// Handle success result
+				s.logger.Info().Str("url", p.url).Msg("Successfully extracted data from URL")
+				successCrawlResult := SuccessCrawlResult{
+					URL:              result.URL,
+					FinalURL:         result.FinalURL,
+					Redirects:        convertRedirects(result.Redirects),
+					HTTPSDowngrade:   result.HTTPSDowngrade,
+					Attempts:         result.Attempts,
+					AttemptErrors:    result.AttemptErrors,
+					BytesRead:        result.BytesRead,
+					Charset:          result.Charset,
+					Title:            result.Title,
+					MetaDescriptions: result.MetaDescriptions,
+					Links:            convertLinks(result.Links),
+					KeywordCounts:    result.KeywordCounts,
+					KeywordResults:   convertKeywordResults(result.KeywordResults),
+					WordCount:        result.WordCount,
+					Source:           string(result.Source),
+					Depth:            p.depth,
+					DiscoveredFrom:   p.discoveredFrom,
+					LinkChecks:       pageLinkChecks,
+				}
+				if result.Source == extractor.SourceCache || result.Source == extractor.SourceRevalidated {
+					cacheAge := result.CacheAge
+					successCrawlResult.CacheAge = &cacheAge
+				}
+				successCrawlResults = append(successCrawlResults, successCrawlResult)
+				if opts.OnSuccess != nil {
+					opts.OnSuccess(successCrawlResult)
+				}
+
+				// The page the URL redirected to is crawled already
+				finalURL := result.FinalURL
+				if finalURL == "" {
+					finalURL = p.url
+				}
+				visited[utils.NormalizeURL(finalURL)] = true
+
+				// Links of pages redirecting away from the domain aren't followed
+				if p.depth >= maxDepth || utils.RegistrableDomain(finalURL) != utils.RegistrableDomain(p.url) {
+					return nil
+				}
+
+				// Queue same domain links for the next depth, extracted links are already resolved and normalized
+				for _, link := range result.Links {
+					if scheduled >= maxPages {
+						break
+					}
+
+					if link.Class == extractor.LinkExternal || visited[link.URL] {
+						continue
+					}
+
+					visited[link.URL] = true
+					scheduled++
+					nextFrontier = append(nextFrontier, page{
+						url:            link.URL,
+						depth:          p.depth + 1,
+						discoveredFrom: p.url,
+					})
+					if opts.OnScheduled != nil {
+						opts.OnScheduled(link.URL)
+					}
+				}
+
+				return nil
+			})
+		}
+
+		// Wait for all requests in this depth to finish
+		err := eg.Wait()
+		if err != nil {
+			// This is actually not gonna happen as we are not returning any error from the goroutines
+			// But handling the error just in case
+			return nil, nil, err
+		}
+
+		frontier = nextFrontier
 	}
 
 	// Return both success and error results
 	return successCrawlResults, errorCrawlResults, nil
 }
+
+// extract checks the URL policy and the robots.txt of the page's host, unless told to ignore it, and waits
+// for its host and one of the crawl's slots before extracting its data
+func (s *crawlService) extract(ctx context.Context, p page, keywords []string, opts CrawlOptions, slots chan struct{}) (*extractor.ExtractResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Hostnames resolving to blocked addresses are only caught when dialing, by the HTTP client
+	if err := s.urlPolicy.CheckURL(p.url); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(p.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	var crawlDelay time.Duration
+	if !opts.IgnoreRobots {
+		decision, err := s.robotsChecker.Check(ctx, p.url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check robots.txt: %w", err)
+		}
+
+		if !decision.Allowed {
+			return nil, errRobotsDisallowed
+		}
+		crawlDelay = decision.CrawlDelay
+	}
+
+	release, err := s.hostScheduler.acquire(ctx, u.Host, crawlDelay)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	select {
+	case slots <- struct{}{}:
+		defer func() { <-slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	s.logger.Info().Str("url", p.url).Int("depth", p.depth).Msg("Extracting data from URL")
+	return s.extractorClient.Extract(ctx, p.url, keywords, extractor.Options{
+		Cache:               opts.Cache,
+		StripTrackingParams: opts.StripTrackingParams,
+		Redirects:           opts.Redirects,
+		UserAgent:           opts.UserAgent,
+		Headers:             opts.Headers,
+		KeywordOptions:      opts.KeywordOptions,
+		MaxKeywordMatches:   opts.MaxKeywordMatches,
+		Language:            opts.Language,
+	})
+}
+
+// convertKeywordResults returns nil when there were no keywords
+func convertKeywordResults(keywordResults map[string]extractor.KeywordResult) map[string]KeywordResult {
+	if len(keywordResults) == 0 {
+		return nil
+	}
+
+	converted := make(map[string]KeywordResult, len(keywordResults))
+	for keyword, keywordResult := range keywordResults {
+		converted[keyword] = KeywordResult{
+			Count:           keywordResult.Count,
+			Regions:         KeywordRegions(keywordResult.Regions),
+			Density:         keywordResult.Density,
+			Mode:            string(keywordResult.Options.Mode),
+			CaseInsensitive: keywordResult.Options.CaseInsensitive,
+			FoldDiacritics:  keywordResult.Options.FoldDiacritics,
+			Language:        keywordResult.Language,
+			Matches:         convertKeywordMatches(keywordResult.Matches),
+			Error:           keywordResult.Error,
+		}
+	}
+	return converted
+}
+
+// convertKeywordMatches returns nil when matches weren't asked for
+func convertKeywordMatches(matches []extractor.KeywordMatch) []KeywordMatch {
+	if matches == nil {
+		return nil
+	}
+
+	converted := make([]KeywordMatch, 0, len(matches))
+	for _, match := range matches {
+		converted = append(converted, KeywordMatch(match))
+	}
+	return converted
+}
+
+// convertRedirects returns nil when there were no redirects
+func convertRedirects(redirects []extractor.Redirect) []Redirect {
+	if len(redirects) == 0 {
+		return nil
+	}
+
+	converted := make([]Redirect, 0, len(redirects))
+	for _, redirect := range redirects {
+		converted = append(converted, Redirect{
+			URL:        redirect.URL,
+			StatusCode: redirect.StatusCode,
+			Location:   redirect.Location,
+			Downgrade:  redirect.Downgrade,
+		})
+	}
+
+	return converted
+}
+
+func redirectErrorReason(err *extractor.RedirectError) ErrorReason {
+	switch {
+	case errors.Is(err, extractor.ErrRedirectLoop):
+		return ErrorReasonRedirectLoop
+	case errors.Is(err, extractor.ErrTooManyRedirects):
+		return ErrorReasonTooManyRedirects
+	default:
+		return ErrorReasonRedirectNotFollowed
+	}
+}
+
+func convertLinks(links []extractor.Link) []Link {
+	converted := make([]Link, 0, len(links))
+	for _, link := range links {
+		converted = append(converted, Link{
+			URL:   link.URL,
+			Text:  link.Text,
+			Rel:   link.Rel,
+			Class: string(link.Class),
+		})
+	}
+
+	return converted
+}
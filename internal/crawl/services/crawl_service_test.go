@@ -2,29 +2,38 @@ package services_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jponc/domain-crawler/internal/crawl/services"
 	"github.com/jponc/domain-crawler/internal/extractor"
+	"github.com/jponc/domain-crawler/internal/linkchecker"
+	"github.com/jponc/domain-crawler/internal/metrics"
+	"github.com/jponc/domain-crawler/internal/robots"
+	"github.com/jponc/domain-crawler/internal/urlpolicy"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 )
 
 // Mocks
 type mockExtractorClient struct {
-	extractFn func(ctx context.Context, url string, keywords []string) (*extractor.ExtractResult, error)
+	extractFn func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error)
 }
 
-func (m *mockExtractorClient) Extract(ctx context.Context, url string, keywords []string) (*extractor.ExtractResult, error) {
+func (m *mockExtractorClient) Extract(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
 	if m != nil && m.extractFn != nil {
-		return m.extractFn(ctx, url, keywords)
+		return m.extractFn(ctx, url, keywords, opts)
 	}
 
 	return &extractor.ExtractResult{
 		URL:              url,
 		Title:            "Title",
 		MetaDescriptions: []string{"Meta Description 1", "Meta Description 2"},
-		Links:            []string{"https://link1.com", "https://link2.com"},
+		Links:            []extractor.Link{extractedLink("https://link1.com/", extractor.LinkExternal)},
 		KeywordCounts: map[string]int{
 			"keyword1": 1,
 			"keyword2": 2,
@@ -32,14 +41,86 @@ func (m *mockExtractorClient) Extract(ctx context.Context, url string, keywords
 	}, nil
 }
 
+type mockLinkChecker struct {
+	checkFn func(ctx context.Context, urls []string) []linkchecker.Result
+}
+
+func (m *mockLinkChecker) Check(ctx context.Context, urls []string) []linkchecker.Result {
+	if m != nil && m.checkFn != nil {
+		return m.checkFn(ctx, urls)
+	}
+
+	results := []linkchecker.Result{}
+	for _, url := range urls {
+		results = append(results, linkchecker.Result{URL: url, Method: "HEAD", StatusCode: 200})
+	}
+	return results
+}
+
+type mockRobotsChecker struct {
+	checkFn func(ctx context.Context, url string) (robots.Decision, error)
+}
+
+func (m *mockRobotsChecker) Check(ctx context.Context, url string) (robots.Decision, error) {
+	if m != nil && m.checkFn != nil {
+		return m.checkFn(ctx, url)
+	}
+
+	return robots.Decision{Allowed: true}, nil
+}
+
+type mockURLPolicy struct {
+	checkURLFn func(url string) error
+}
+
+func (m *mockURLPolicy) CheckURL(url string) error {
+	if m != nil && m.checkURLFn != nil {
+		return m.checkURLFn(url)
+	}
+
+	return nil
+}
+
+func extractedLink(url string, class extractor.LinkClass) extractor.Link {
+	return extractor.Link{URL: url, Rel: []string{}, Class: class}
+}
+
+func crawledLink(url string, class extractor.LinkClass) services.Link {
+	return services.Link{URL: url, Rel: []string{}, Class: string(class)}
+}
+
+// mockSite returns an extractor client serving the given url to links mapping
+func mockSite(pages map[string][]extractor.Link) *mockExtractorClient {
+	return &mockExtractorClient{
+		extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+			links, ok := pages[url]
+			if !ok {
+				return nil, fmt.Errorf("not found")
+			}
+
+			return &extractor.ExtractResult{
+				URL:           url,
+				Links:         links,
+				KeywordCounts: map[string]int{},
+			}, nil
+		},
+	}
+}
+
 // Tests
 
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
+
 func TestCrawlService_Crawl(t *testing.T) {
 	tests := []struct {
 		name                        string
 		urls                        []string
 		keywords                    []string
+		opts                        services.CrawlOptions
 		mockExtractorClient         *mockExtractorClient
+		mockURLPolicy               *mockURLPolicy
 		expectedSuccessCrawlResults []services.SuccessCrawlResult
 		expectedErrorCrawlResults   []services.ErrorCrawlResult
 	}{
@@ -48,7 +129,7 @@ func TestCrawlService_Crawl(t *testing.T) {
 			urls:     []string{"http://example.com"},
 			keywords: []string{"keyword1", "keyword2"},
 			mockExtractorClient: &mockExtractorClient{
-				extractFn: func(ctx context.Context, url string, keywords []string) (*extractor.ExtractResult, error) {
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
 					return nil, fmt.Errorf("failed to extract data")
 				},
 			},
@@ -65,12 +146,12 @@ func TestCrawlService_Crawl(t *testing.T) {
 			urls:     []string{"http://example.com"},
 			keywords: []string{"keyword1", "keyword2"},
 			mockExtractorClient: &mockExtractorClient{
-				extractFn: func(ctx context.Context, url string, keywords []string) (*extractor.ExtractResult, error) {
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
 					return &extractor.ExtractResult{
 						URL:              url,
 						Title:            "Title",
 						MetaDescriptions: []string{"Meta Description 1", "Meta Description 2"},
-						Links:            []string{"https://link1.com", "https://link2.com"},
+						Links:            []extractor.Link{extractedLink("https://link1.com/", extractor.LinkExternal)},
 						KeywordCounts: map[string]int{
 							"keyword1": 1,
 							"keyword2": 2,
@@ -83,7 +164,7 @@ func TestCrawlService_Crawl(t *testing.T) {
 					URL:              "http://example.com",
 					Title:            "Title",
 					MetaDescriptions: []string{"Meta Description 1", "Meta Description 2"},
-					Links:            []string{"https://link1.com", "https://link2.com"},
+					Links:            []services.Link{crawledLink("https://link1.com/", extractor.LinkExternal)},
 					KeywordCounts: map[string]int{
 						"keyword1": 1,
 						"keyword2": 2,
@@ -92,18 +173,49 @@ func TestCrawlService_Crawl(t *testing.T) {
 			},
 			expectedErrorCrawlResults: []services.ErrorCrawlResult{},
 		},
+		{
+			name:     "passes the cache policy and reports the age of cached documents",
+			urls:     []string{"http://example.com"},
+			keywords: []string{},
+			opts:     services.CrawlOptions{Cache: extractor.CachePolicy{Mode: extractor.CacheModeRefresh}},
+			mockExtractorClient: &mockExtractorClient{
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+					require.Equal(t, extractor.Options{Cache: extractor.CachePolicy{Mode: extractor.CacheModeRefresh}}, opts)
+
+					return &extractor.ExtractResult{
+						URL:              url,
+						MetaDescriptions: []string{},
+						Links:            []extractor.Link{},
+						KeywordCounts:    map[string]int{},
+						Source:           extractor.SourceRevalidated,
+						CacheAge:         time.Minute,
+					}, nil
+				},
+			},
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{
+				{
+					URL:              "http://example.com",
+					MetaDescriptions: []string{},
+					Links:            []services.Link{},
+					KeywordCounts:    map[string]int{},
+					Source:           "revalidated",
+					CacheAge:         durationPtr(time.Minute),
+				},
+			},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{},
+		},
 		{
 			name:     "returns success and error crawl results when successfully extracted data from some URLs and failed to extract data from others",
 			urls:     []string{"http://example.com", "http://example.com/404"},
 			keywords: []string{"keyword1", "keyword2"},
 			mockExtractorClient: &mockExtractorClient{
-				extractFn: func(ctx context.Context, url string, keywords []string) (*extractor.ExtractResult, error) {
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
 					if url == "http://example.com" {
 						return &extractor.ExtractResult{
 							URL:              url,
 							Title:            "Title",
 							MetaDescriptions: []string{"Meta Description 1", "Meta Description 2"},
-							Links:            []string{"https://link1.com", "https://link2.com"},
+							Links:            []extractor.Link{extractedLink("https://link1.com/", extractor.LinkExternal)},
 							KeywordCounts: map[string]int{
 								"keyword1": 1,
 								"keyword2": 2,
@@ -119,7 +231,7 @@ func TestCrawlService_Crawl(t *testing.T) {
 					URL:              "http://example.com",
 					Title:            "Title",
 					MetaDescriptions: []string{"Meta Description 1", "Meta Description 2"},
-					Links:            []string{"https://link1.com", "https://link2.com"},
+					Links:            []services.Link{crawledLink("https://link1.com/", extractor.LinkExternal)},
 					KeywordCounts: map[string]int{
 						"keyword1": 1,
 						"keyword2": 2,
@@ -133,19 +245,820 @@ func TestCrawlService_Crawl(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:     "follows same domain links up to max depth when crawling recursively",
+			urls:     []string{"http://example.com"},
+			keywords: []string{"keyword1"},
+			opts: services.CrawlOptions{
+				Recursive: true,
+				MaxDepth:  1,
+			},
+			mockExtractorClient: mockSite(map[string][]extractor.Link{
+				"http://example.com": {
+					extractedLink("http://example.com/a", extractor.LinkInternal),
+					extractedLink("https://blog.example.com/b", extractor.LinkSubdomain),
+					extractedLink("https://other.com/c", extractor.LinkExternal),
+					extractedLink("http://example.com/", extractor.LinkInternal),
+				},
+				"http://example.com/a":       {extractedLink("http://example.com/a/deep", extractor.LinkInternal)},
+				"https://blog.example.com/b": {},
+				"http://example.com/a/deep":  {},
+				"https://other.com/c":        {},
+			}),
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{
+				{
+					URL: "http://example.com",
+					Links: []services.Link{
+						crawledLink("http://example.com/a", extractor.LinkInternal),
+						crawledLink("https://blog.example.com/b", extractor.LinkSubdomain),
+						crawledLink("https://other.com/c", extractor.LinkExternal),
+						crawledLink("http://example.com/", extractor.LinkInternal),
+					},
+					KeywordCounts: map[string]int{},
+					Depth:         0,
+				},
+				{
+					URL:            "http://example.com/a",
+					Links:          []services.Link{crawledLink("http://example.com/a/deep", extractor.LinkInternal)},
+					KeywordCounts:  map[string]int{},
+					Depth:          1,
+					DiscoveredFrom: "http://example.com",
+				},
+				{
+					URL:            "https://blog.example.com/b",
+					Links:          []services.Link{},
+					KeywordCounts:  map[string]int{},
+					Depth:          1,
+					DiscoveredFrom: "http://example.com",
+				},
+			},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{},
+		},
+		{
+			name:     "stops at max pages when crawling recursively",
+			urls:     []string{"http://example.com"},
+			keywords: []string{"keyword1"},
+			opts: services.CrawlOptions{
+				Recursive: true,
+				MaxDepth:  5,
+				MaxPages:  2,
+			},
+			mockExtractorClient: mockSite(map[string][]extractor.Link{
+				"http://example.com": {
+					extractedLink("http://example.com/a", extractor.LinkInternal),
+					extractedLink("http://example.com/b", extractor.LinkInternal),
+				},
+				"http://example.com/a": {extractedLink("http://example.com/c", extractor.LinkInternal)},
+			}),
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{
+				{
+					URL: "http://example.com",
+					Links: []services.Link{
+						crawledLink("http://example.com/a", extractor.LinkInternal),
+						crawledLink("http://example.com/b", extractor.LinkInternal),
+					},
+					KeywordCounts: map[string]int{},
+					Depth:         0,
+				},
+				{
+					URL:            "http://example.com/a",
+					Links:          []services.Link{crawledLink("http://example.com/c", extractor.LinkInternal)},
+					KeywordCounts:  map[string]int{},
+					Depth:          1,
+					DiscoveredFrom: "http://example.com",
+				},
+			},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{},
+		},
+		{
+			name:     "reports depth and parent of discovered pages that fail",
+			urls:     []string{"http://example.com"},
+			keywords: []string{"keyword1"},
+			opts: services.CrawlOptions{
+				Recursive: true,
+			},
+			mockExtractorClient: mockSite(map[string][]extractor.Link{
+				"http://example.com": {extractedLink("http://example.com/missing", extractor.LinkInternal)},
+			}),
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{
+				{
+					URL:           "http://example.com",
+					Links:         []services.Link{crawledLink("http://example.com/missing", extractor.LinkInternal)},
+					KeywordCounts: map[string]int{},
+					Depth:         0,
+				},
+			},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{
+				{
+					URL:            "http://example.com/missing",
+					Error:          "not found",
+					Depth:          1,
+					DiscoveredFrom: "http://example.com",
+				},
+			},
+		},
+		{
+			name:     "passes the redirect policy and header overrides and reports redirect errors",
+			urls:     []string{"http://example.com"},
+			keywords: []string{},
+			opts: services.CrawlOptions{
+				Redirects: extractor.RedirectPolicy{MaxRedirects: 3},
+				UserAgent: "custom/1.0",
+				Headers:   map[string]string{"Accept-Language": "fr"},
+			},
+			mockExtractorClient: &mockExtractorClient{
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+					require.Equal(t, extractor.Options{
+						Redirects: extractor.RedirectPolicy{MaxRedirects: 3},
+						UserAgent: "custom/1.0",
+						Headers:   map[string]string{"Accept-Language": "fr"},
+					}, opts)
+
+					return nil, fmt.Errorf("failed to fetch html: %w", &extractor.RedirectError{
+						Err: extractor.ErrRedirectLoop,
+						Redirects: []extractor.Redirect{
+							{URL: "http://example.com", StatusCode: 302, Location: "/a"},
+							{URL: "http://example.com/a", StatusCode: 302, Location: "/"},
+						},
+					})
+				},
+			},
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{
+				{
+					URL:    "http://example.com",
+					Error:  "failed to fetch html: redirect loop after 2 redirect(s)",
+					Reason: services.ErrorReasonRedirectLoop,
+					Redirects: []services.Redirect{
+						{URL: "http://example.com", StatusCode: 302, Location: "/a"},
+						{URL: "http://example.com/a", StatusCode: 302, Location: "/"},
+					},
+				},
+			},
+		},
+		{
+			name:     "passes keyword options and reports keyword results by region",
+			urls:     []string{"http://example.com"},
+			keywords: []string{"cat", "dog"},
+			opts: services.CrawlOptions{
+				KeywordOptions: map[string]extractor.KeywordOptions{
+					"cat": {Mode: extractor.KeywordModeWholeWord, CaseInsensitive: true},
+				},
+				MaxKeywordMatches: 1,
+			},
+			mockExtractorClient: &mockExtractorClient{
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+					require.Equal(t, map[string]extractor.KeywordOptions{
+						"cat": {Mode: extractor.KeywordModeWholeWord, CaseInsensitive: true},
+					}, opts.KeywordOptions)
+					require.Equal(t, 1, opts.MaxKeywordMatches)
+
+					return &extractor.ExtractResult{
+						URL:           url,
+						Links:         []extractor.Link{},
+						KeywordCounts: map[string]int{"cat": 3, "dog": 1},
+						KeywordResults: map[string]extractor.KeywordResult{
+							"cat": {
+								Count:   3,
+								Regions: extractor.KeywordRegions{Title: 1, H2: 1, Body: 3, ImageAlt: 2},
+								Density: 7.5,
+								Options: extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord, CaseInsensitive: true},
+								Matches: []extractor.KeywordMatch{{Offset: 12, Length: 3, Snippet: "Why our cat loves it", Path: "main > p"}},
+							},
+							"dog": {
+								Count:   1,
+								Regions: extractor.KeywordRegions{Body: 1},
+								Density: 2.5,
+								Options: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring},
+								Matches: []extractor.KeywordMatch{{Offset: 30, Length: 3, Snippet: "and the dog", Path: "main > p"}},
+							},
+						},
+						WordCount: 40,
+					}, nil
+				},
+			},
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{
+				{
+					URL:           "http://example.com",
+					Links:         []services.Link{},
+					KeywordCounts: map[string]int{"cat": 3, "dog": 1},
+					KeywordResults: map[string]services.KeywordResult{
+						"cat": {
+							Count:           3,
+							Regions:         services.KeywordRegions{Title: 1, H2: 1, Body: 3, ImageAlt: 2},
+							Density:         7.5,
+							Mode:            "whole_word",
+							CaseInsensitive: true,
+							Matches:         []services.KeywordMatch{{Offset: 12, Length: 3, Snippet: "Why our cat loves it", Path: "main > p"}},
+						},
+						"dog": {
+							Count:   1,
+							Regions: services.KeywordRegions{Body: 1},
+							Density: 2.5,
+							Mode:    "substring",
+							Matches: []services.KeywordMatch{{Offset: 30, Length: 3, Snippet: "and the dog", Path: "main > p"}},
+						},
+					},
+					WordCount: 40,
+				},
+			},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{},
+		},
+		{
+			name:     "passes the language of stemmed keywords",
+			urls:     []string{"http://example.com"},
+			keywords: []string{"chanteuse"},
+			opts: services.CrawlOptions{
+				KeywordOptions: map[string]extractor.KeywordOptions{
+					"chanteuse": {Mode: extractor.KeywordModeStemmed},
+				},
+				Language: "fr",
+			},
+			mockExtractorClient: &mockExtractorClient{
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+					require.Equal(t, "fr", opts.Language)
+
+					return &extractor.ExtractResult{
+						URL:           url,
+						Links:         []extractor.Link{},
+						KeywordCounts: map[string]int{"chanteuse": 2},
+						KeywordResults: map[string]extractor.KeywordResult{
+							"chanteuse": {
+								Count:    2,
+								Regions:  extractor.KeywordRegions{Body: 2},
+								Density:  25,
+								Options:  extractor.KeywordOptions{Mode: extractor.KeywordModeStemmed, CaseInsensitive: true},
+								Language: "fr",
+							},
+						},
+						WordCount: 8,
+					}, nil
+				},
+			},
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{
+				{
+					URL:           "http://example.com",
+					Links:         []services.Link{},
+					KeywordCounts: map[string]int{"chanteuse": 2},
+					KeywordResults: map[string]services.KeywordResult{
+						"chanteuse": {
+							Count:           2,
+							Regions:         services.KeywordRegions{Body: 2},
+							Density:         25,
+							Mode:            "stemmed",
+							CaseInsensitive: true,
+							Language:        "fr",
+						},
+					},
+					WordCount: 8,
+				},
+			},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{},
+		},
+		{
+			name:     "reports fetch attempts of successes and errors",
+			urls:     []string{"http://example.com", "http://example.com/down"},
+			keywords: []string{},
+			mockExtractorClient: &mockExtractorClient{
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+					if url == "http://example.com/down" {
+						return nil, fmt.Errorf("failed to fetch html: %w", &extractor.AttemptsError{
+							Errors: []string{"unexpected status code: 503", "unexpected status code: 503"},
+							Err:    errors.New("unexpected status code: 503"),
+						})
+					}
+
+					return &extractor.ExtractResult{
+						URL:           url,
+						Attempts:      2,
+						AttemptErrors: []string{"unexpected status code: 429"},
+						Links:         []extractor.Link{},
+						KeywordCounts: map[string]int{},
+					}, nil
+				},
+			},
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{
+				{
+					URL:           "http://example.com",
+					Attempts:      2,
+					AttemptErrors: []string{"unexpected status code: 429"},
+					Links:         []services.Link{},
+					KeywordCounts: map[string]int{},
+				},
+			},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{
+				{
+					URL:           "http://example.com/down",
+					Error:         "failed to fetch html: unexpected status code: 503 (after 2 attempts)",
+					Attempts:      2,
+					AttemptErrors: []string{"unexpected status code: 503", "unexpected status code: 503"},
+				},
+			},
+		},
+		{
+			name:     "reports URLs failed fast by an open circuit",
+			urls:     []string{"http://down.com"},
+			keywords: []string{},
+			mockExtractorClient: &mockExtractorClient{
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+					return nil, fmt.Errorf("failed to fetch html: %w", &extractor.AttemptsError{
+						Errors: []string{"circuit open for host down.com"},
+						Err:    fmt.Errorf("%w for host down.com", extractor.ErrCircuitOpen),
+					})
+				},
+			},
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{
+				{
+					URL:           "http://down.com",
+					Error:         "failed to fetch html: circuit open for host down.com",
+					Reason:        services.ErrorReasonCircuitOpen,
+					Attempts:      1,
+					AttemptErrors: []string{"circuit open for host down.com"},
+				},
+			},
+		},
+		{
+			name:     "reports pages too large or which aren't HTML",
+			urls:     []string{"http://example.com", "http://example.com/huge", "http://example.com/image"},
+			keywords: []string{},
+			mockExtractorClient: &mockExtractorClient{
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+					switch url {
+					case "http://example.com/huge":
+						return nil, fmt.Errorf("failed to fetch html: %w", &extractor.AttemptsError{
+							Errors:    []string{"response body too large: over the limit of 10 bytes"},
+							Err:       fmt.Errorf("%w: over the limit of 10 bytes", extractor.ErrBodyTooLarge),
+							BytesRead: 11,
+						})
+					case "http://example.com/image":
+						return nil, fmt.Errorf("failed to fetch html: %w", &extractor.AttemptsError{
+							Errors:    []string{"unsupported content type: image/png"},
+							Err:       &extractor.ContentTypeError{ContentType: "image/png"},
+							BytesRead: 512,
+						})
+					}
+
+					return &extractor.ExtractResult{URL: url, BytesRead: 42, Charset: "shift_jis", Links: []extractor.Link{}, KeywordCounts: map[string]int{}}, nil
+				},
+			},
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{
+				{
+					URL:           "http://example.com",
+					BytesRead:     42,
+					Charset:       "shift_jis",
+					Links:         []services.Link{},
+					KeywordCounts: map[string]int{},
+				},
+			},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{
+				{
+					URL:           "http://example.com/huge",
+					Error:         "failed to fetch html: response body too large: over the limit of 10 bytes",
+					Reason:        services.ErrorReasonBodyTooLarge,
+					Attempts:      1,
+					AttemptErrors: []string{"response body too large: over the limit of 10 bytes"},
+					BytesRead:     11,
+				},
+				{
+					URL:           "http://example.com/image",
+					Error:         "failed to fetch html: unsupported content type: image/png",
+					Reason:        services.ErrorReasonUnsupportedContentType,
+					ContentType:   "image/png",
+					Attempts:      1,
+					AttemptErrors: []string{"unsupported content type: image/png"},
+					BytesRead:     512,
+				},
+			},
+		},
+		{
+			name:     "reports URLs blocked by the URL policy without fetching them",
+			urls:     []string{"http://169.254.169.254/latest/meta-data/", "http://internal.example.com"},
+			keywords: []string{},
+			mockExtractorClient: &mockExtractorClient{
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+					require.Equal(t, "http://internal.example.com", url)
+
+					// Hostnames resolving to internal addresses are blocked when dialing
+					return nil, fmt.Errorf("failed to fetch html: %w", fmt.Errorf("%w: address 10.0.0.1 is internal", urlpolicy.ErrBlocked))
+				},
+			},
+			mockURLPolicy: &mockURLPolicy{
+				checkURLFn: func(url string) error {
+					if url == "http://169.254.169.254/latest/meta-data/" {
+						return fmt.Errorf("%w: address 169.254.169.254 is internal", urlpolicy.ErrBlocked)
+					}
+					return nil
+				},
+			},
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{
+				{
+					URL:    "http://169.254.169.254/latest/meta-data/",
+					Error:  "url blocked: address 169.254.169.254 is internal",
+					Reason: services.ErrorReasonBlocked,
+				},
+				{
+					URL:    "http://internal.example.com",
+					Error:  "failed to fetch html: url blocked: address 10.0.0.1 is internal",
+					Reason: services.ErrorReasonBlocked,
+				},
+			},
+		},
+		{
+			name:     "reports the redirect chain and doesn't crawl the final URL again",
+			urls:     []string{"http://example.com"},
+			keywords: []string{},
+			opts:     services.CrawlOptions{Recursive: true},
+			mockExtractorClient: &mockExtractorClient{
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+					if url != "http://example.com" {
+						return &extractor.ExtractResult{URL: url, FinalURL: url, Links: []extractor.Link{}, KeywordCounts: map[string]int{}}, nil
+					}
+
+					return &extractor.ExtractResult{
+						URL:       url,
+						FinalURL:  "http://www.example.com/",
+						Redirects: []extractor.Redirect{{URL: "http://example.com", StatusCode: 301, Location: "http://www.example.com/"}},
+						Links: []extractor.Link{
+							extractedLink("http://www.example.com/", extractor.LinkInternal),
+							extractedLink("http://www.example.com/a", extractor.LinkInternal),
+						},
+						KeywordCounts: map[string]int{},
+					}, nil
+				},
+			},
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{
+				{
+					URL:       "http://example.com",
+					FinalURL:  "http://www.example.com/",
+					Redirects: []services.Redirect{{URL: "http://example.com", StatusCode: 301, Location: "http://www.example.com/"}},
+					Links: []services.Link{
+						crawledLink("http://www.example.com/", extractor.LinkInternal),
+						crawledLink("http://www.example.com/a", extractor.LinkInternal),
+					},
+					KeywordCounts: map[string]int{},
+				},
+				{
+					URL:            "http://www.example.com/a",
+					FinalURL:       "http://www.example.com/a",
+					Links:          []services.Link{},
+					KeywordCounts:  map[string]int{},
+					Depth:          1,
+					DiscoveredFrom: "http://example.com",
+				},
+			},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{},
+		},
+		{
+			name:     "doesn't follow links of pages redirecting to another domain",
+			urls:     []string{"http://example.com"},
+			keywords: []string{},
+			opts:     services.CrawlOptions{Recursive: true},
+			mockExtractorClient: &mockExtractorClient{
+				extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+					return &extractor.ExtractResult{
+						URL:           url,
+						FinalURL:      "https://other.com/",
+						Links:         []extractor.Link{extractedLink("https://other.com/a", extractor.LinkInternal)},
+						KeywordCounts: map[string]int{},
+					}, nil
+				},
+			},
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{
+				{
+					URL:           "http://example.com",
+					FinalURL:      "https://other.com/",
+					Links:         []services.Link{crawledLink("https://other.com/a", extractor.LinkInternal)},
+					KeywordCounts: map[string]int{},
+				},
+			},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			crawlService := services.NewCrawlService(tt.mockExtractorClient, 1)
+			crawlService := services.NewCrawlService(tt.mockExtractorClient, &mockLinkChecker{}, &mockRobotsChecker{}, services.NewHostScheduler(10, 0), tt.mockURLPolicy, 1)
 
-			crawlSuccessResults, crawlErrorResults, err := crawlService.Crawl(context.Background(), tt.urls, tt.keywords)
+			crawlSuccessResults, crawlErrorResults, err := crawlService.Crawl(context.Background(), tt.urls, tt.keywords, tt.opts)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
 
-			require.Equal(t, tt.expectedSuccessCrawlResults, crawlSuccessResults)
+			// Pages waiting for their host don't hold a slot, so results come in completion order
+			require.ElementsMatch(t, tt.expectedSuccessCrawlResults, crawlSuccessResults)
+			require.ElementsMatch(t, tt.expectedErrorCrawlResults, crawlErrorResults)
+		})
+	}
+}
+
+func TestCrawlService_CrawlReportsUnprocessedURLsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	extracted := make(chan struct{})
+	mockExtractorClient := &mockExtractorClient{
+		extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+			if url == "http://example.com/slow" {
+				// Cancel the crawl while this fetch is in flight, once the other seed is extracted
+				<-extracted
+				cancel()
+				<-ctx.Done()
+				return nil, fmt.Errorf("failed to fetch html: %w", ctx.Err())
+			}
+
+			defer close(extracted)
+			return &extractor.ExtractResult{
+				URL:           url,
+				Links:         []extractor.Link{extractedLink("http://example.com/never", extractor.LinkInternal)},
+				KeywordCounts: map[string]int{},
+			}, nil
+		},
+	}
+
+	crawlService := services.NewCrawlService(mockExtractorClient, &mockLinkChecker{}, &mockRobotsChecker{}, services.NewHostScheduler(10, 0), &mockURLPolicy{}, 2)
+
+	urls := []string{"http://example.com", "http://example.com/slow"}
+	crawlSuccessResults, crawlErrorResults, err := crawlService.Crawl(ctx, urls, nil, services.CrawlOptions{Recursive: true})
+	require.NoError(t, err)
+
+	require.Equal(t, []services.SuccessCrawlResult{
+		{
+			URL:           "http://example.com",
+			Links:         []services.Link{crawledLink("http://example.com/never", extractor.LinkInternal)},
+			KeywordCounts: map[string]int{},
+		},
+	}, crawlSuccessResults)
+	require.Equal(t, []services.ErrorCrawlResult{
+		{URL: "http://example.com/slow", Error: "failed to fetch html: context canceled", Reason: services.ErrorReasonCancelled},
+		{URL: "http://example.com/never", Error: "context canceled", Reason: services.ErrorReasonCancelled, Depth: 1, DiscoveredFrom: "http://example.com"},
+	}, crawlErrorResults)
+}
+
+func TestCrawlService_CrawlReportsScheduledURLs(t *testing.T) {
+	mockExtractorClient := mockSite(map[string][]extractor.Link{
+		"http://example.com": {
+			extractedLink("http://example.com/a", extractor.LinkInternal),
+			extractedLink("http://example.com/b", extractor.LinkInternal),
+			extractedLink("http://other.com/", extractor.LinkExternal),
+		},
+		"http://example.com/a": {
+			extractedLink("http://example.com/b", extractor.LinkInternal),
+			extractedLink("http://example.com/c", extractor.LinkInternal),
+		},
+	})
+	crawlService := services.NewCrawlService(mockExtractorClient, &mockLinkChecker{}, &mockRobotsChecker{}, services.NewHostScheduler(10, 0), &mockURLPolicy{}, 2)
+
+	// Every URL is scheduled once, as soon as it is queued
+	scheduled := []string{}
+	opts := services.CrawlOptions{
+		Recursive: true,
+		MaxPages:  4,
+		OnScheduled: func(url string) {
+			scheduled = append(scheduled, url)
+		},
+	}
+
+	crawlSuccessResults, crawlErrorResults, err := crawlService.Crawl(context.Background(), []string{"http://example.com", "http://example.com"}, nil, opts)
+	require.NoError(t, err)
+	require.Equal(t, len(scheduled), len(crawlSuccessResults)+len(crawlErrorResults))
+	require.ElementsMatch(t, []string{"http://example.com", "http://example.com/a", "http://example.com/b", "http://example.com/c"}, scheduled)
+}
+
+func TestCrawlService_CrawlFollowsRobots(t *testing.T) {
+	mockRobotsChecker := &mockRobotsChecker{
+		checkFn: func(ctx context.Context, url string) (robots.Decision, error) {
+			return robots.Decision{Allowed: url != "http://example.com/private", CrawlDelay: 50 * time.Millisecond}, nil
+		},
+	}
+
+	tests := []struct {
+		name                      string
+		opts                      services.CrawlOptions
+		expectedSucceeded         []string
+		expectedErrorCrawlResults []services.ErrorCrawlResult
+		expectedMinDuration       time.Duration
+	}{
+		{
+			name:              "skips disallowed URLs and spaces requests by the crawl delay",
+			expectedSucceeded: []string{"http://example.com", "http://example.com/public"},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{
+				{URL: "http://example.com/private", Error: "disallowed by robots.txt", Reason: services.ErrorReasonRobotsDisallowed},
+			},
+			// The second allowed request waits for the crawl delay of the first one
+			expectedMinDuration: 50 * time.Millisecond,
+		},
+		{
+			name:                      "crawls disallowed URLs when told to ignore robots.txt",
+			opts:                      services.CrawlOptions{IgnoreRobots: true},
+			expectedSucceeded:         []string{"http://example.com", "http://example.com/private", "http://example.com/public"},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crawlService := services.NewCrawlService(&mockExtractorClient{}, &mockLinkChecker{}, mockRobotsChecker, services.NewHostScheduler(10, 0), &mockURLPolicy{}, 3)
+
+			start := time.Now()
+			urls := []string{"http://example.com", "http://example.com/private", "http://example.com/public"}
+			crawlSuccessResults, crawlErrorResults, err := crawlService.Crawl(context.Background(), urls, nil, tt.opts)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, time.Since(start), tt.expectedMinDuration)
+
+			succeeded := []string{}
+			for _, result := range crawlSuccessResults {
+				succeeded = append(succeeded, result.URL)
+			}
+			require.ElementsMatch(t, tt.expectedSucceeded, succeeded)
 			require.Equal(t, tt.expectedErrorCrawlResults, crawlErrorResults)
 		})
 	}
 }
+
+func TestCrawlService_CrawlSchedulesPagesPerHost(t *testing.T) {
+	var mu sync.Mutex
+	running := map[string]int{}
+	maxRunning := map[string]int{}
+	release := make(chan struct{})
+	otherHostDone := make(chan struct{})
+
+	mockExtractorClient := &mockExtractorClient{
+		extractFn: func(ctx context.Context, url string, keywords []string, opts extractor.Options) (*extractor.ExtractResult, error) {
+			host := strings.Split(url, "/")[2]
+
+			mu.Lock()
+			running[host]++
+			maxRunning[host] = max(maxRunning[host], running[host])
+			mu.Unlock()
+
+			if host == "a.com" {
+				// The pages of a.com wait in their host queue while b.com is crawled
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+			} else {
+				close(otherHostDone)
+			}
+
+			mu.Lock()
+			running[host]--
+			mu.Unlock()
+
+			return &extractor.ExtractResult{URL: url, KeywordCounts: map[string]int{}}, nil
+		},
+	}
+
+	crawlService := services.NewCrawlService(mockExtractorClient, &mockLinkChecker{}, &mockRobotsChecker{}, services.NewHostScheduler(1, 0), &mockURLPolicy{}, 2)
+
+	var (
+		crawlSuccessResults []services.SuccessCrawlResult
+		crawlErrorResults   []services.ErrorCrawlResult
+		err                 error
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		urls := []string{"http://a.com/1", "http://a.com/2", "http://a.com/3", "http://b.com/1"}
+		crawlSuccessResults, crawlErrorResults, err = crawlService.Crawl(context.Background(), urls, nil, services.CrawlOptions{})
+	}()
+
+	<-otherHostDone
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.CrawlHostQueueDepth.WithLabelValues("a.com")) == 2
+	}, time.Second, time.Millisecond)
+	close(release)
+
+	<-done
+	require.NoError(t, err)
+	require.Len(t, crawlSuccessResults, 4)
+	require.Empty(t, crawlErrorResults)
+	require.Equal(t, map[string]int{"a.com": 1, "b.com": 1}, maxRunning)
+	require.Equal(t, float64(0), testutil.ToFloat64(metrics.CrawlHostQueueDepth.WithLabelValues("a.com")))
+}
+
+func TestCrawlService_CrawlSpacesRequestsPerHost(t *testing.T) {
+	tests := []struct {
+		name                string
+		delay               time.Duration
+		crawlDelay          time.Duration
+		expectedMinDuration time.Duration
+		expectedMaxDuration time.Duration
+	}{
+		{
+			name:                "waits for the request delay between requests to the same host",
+			delay:               30 * time.Millisecond,
+			expectedMinDuration: 60 * time.Millisecond,
+			expectedMaxDuration: time.Second,
+		},
+		{
+			name:                "uses the robots.txt crawl delay instead of the request delay",
+			delay:               time.Hour,
+			crawlDelay:          30 * time.Millisecond,
+			expectedMinDuration: 60 * time.Millisecond,
+			expectedMaxDuration: time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRobotsChecker := &mockRobotsChecker{
+				checkFn: func(ctx context.Context, url string) (robots.Decision, error) {
+					return robots.Decision{Allowed: true, CrawlDelay: tt.crawlDelay}, nil
+				},
+			}
+			crawlService := services.NewCrawlService(&mockExtractorClient{}, &mockLinkChecker{}, mockRobotsChecker, services.NewHostScheduler(3, tt.delay), &mockURLPolicy{}, 3)
+
+			start := time.Now()
+			urls := []string{"http://example.com/1", "http://example.com/2", "http://example.com/3"}
+			crawlSuccessResults, _, err := crawlService.Crawl(context.Background(), urls, nil, services.CrawlOptions{})
+			require.NoError(t, err)
+			require.Len(t, crawlSuccessResults, 3)
+
+			elapsed := time.Since(start)
+			require.GreaterOrEqual(t, elapsed, tt.expectedMinDuration)
+			require.Less(t, elapsed, tt.expectedMaxDuration)
+		})
+	}
+}
+
+func TestCrawlService_CrawlChecksLinks(t *testing.T) {
+	var mu sync.Mutex
+	checked := map[string]int{}
+	mockLinkChecker := &mockLinkChecker{
+		checkFn: func(ctx context.Context, urls []string) []linkchecker.Result {
+			mu.Lock()
+			defer mu.Unlock()
+
+			results := []linkchecker.Result{}
+			for _, url := range urls {
+				checked[url]++
+
+				switch url {
+				case "http://example.com/gone":
+					results = append(results, linkchecker.Result{URL: url, Method: "GET", StatusCode: 404})
+				case "https://down.com/":
+					results = append(results, linkchecker.Result{URL: url, Method: "HEAD", ErrorClass: linkchecker.ErrorClassDNS, Error: "no such host"})
+				case "http://example.com/old":
+					results = append(results, linkchecker.Result{URL: url, Method: "HEAD", StatusCode: 301, RedirectURL: "http://example.com/a"})
+				default:
+					results = append(results, linkchecker.Result{URL: url, Method: "HEAD", StatusCode: 200})
+				}
+			}
+			return results
+		},
+	}
+
+	mockExtractorClient := mockSite(map[string][]extractor.Link{
+		"http://example.com": {
+			extractedLink("http://example.com/a", extractor.LinkInternal),
+			extractedLink("http://example.com/gone", extractor.LinkInternal),
+			extractedLink("https://down.com/", extractor.LinkExternal),
+		},
+		"http://example.com/a": {
+			extractedLink("http://example.com/gone", extractor.LinkInternal),
+			extractedLink("http://example.com/old", extractor.LinkInternal),
+		},
+		"http://example.com/old": {},
+	})
+
+	crawlService := services.NewCrawlService(mockExtractorClient, mockLinkChecker, &mockRobotsChecker{}, services.NewHostScheduler(10, 0), &mockURLPolicy{}, 2)
+
+	opts := services.CrawlOptions{Recursive: true, MaxDepth: 1, CheckLinks: true}
+	crawlSuccessResults, _, err := crawlService.Crawl(context.Background(), []string{"http://example.com", "http://example.com/a"}, nil, opts)
+	require.NoError(t, err)
+	require.Len(t, crawlSuccessResults, 3)
+
+	// Links found on several pages are checked once
+	for url, count := range checked {
+		require.Equal(t, 1, count, url)
+	}
+
+	for _, result := range crawlSuccessResults {
+		require.Len(t, result.LinkChecks, len(result.Links))
+		for i, linkCheck := range result.LinkChecks {
+			require.Equal(t, result.Links[i].URL, linkCheck.URL)
+		}
+	}
+
+	summary, ok := services.SummarizeLinkChecks(crawlSuccessResults)
+	require.True(t, ok)
+	require.Equal(t, services.LinkCheckSummary{
+		Checked:      4,
+		OK:           1,
+		Redirected:   1,
+		Broken:       2,
+		ErrorClasses: map[string]int{"dns": 1},
+		BrokenLinks: []services.BrokenLink{
+			{URL: "http://example.com/gone", StatusCode: 404, FoundOn: []string{"http://example.com", "http://example.com/a"}},
+			{URL: "https://down.com/", ErrorClass: "dns", FoundOn: []string{"http://example.com"}},
+		},
+	}, summary)
+}
+
+func TestSummarizeLinkChecks_ReturnsFalseWhenLinksWerentChecked(t *testing.T) {
+	_, ok := services.SummarizeLinkChecks([]services.SuccessCrawlResult{{URL: "http://example.com"}})
+	require.False(t, ok)
+}
@@ -1,14 +1,238 @@
 package services
 
+import (
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/extractor"
+)
+
+// CrawlOptions controls how a crawl is performed
+type CrawlOptions struct {
+	// Recursive follows links discovered on each page as long as they stay on the same registrable domain
+	Recursive bool
+	// MaxDepth is the maximum number of links followed away from a seed URL, only used when Recursive is set
+	MaxDepth int
+	// MaxPages is the maximum number of pages fetched in total, only used when Recursive is set
+	MaxPages int
+	// Cache controls whether cached documents are used, refreshed or bypassed
+	Cache extractor.CachePolicy
+	// StripTrackingParams removes analytics query parameters such as utm_source from extracted links
+	StripTrackingParams bool
+	// CheckLinks checks every link found on a page and reports them in SuccessCrawlResult.LinkChecks
+	CheckLinks bool
+	// Redirects controls whether and how far redirects are followed
+	Redirects extractor.RedirectPolicy
+	// IgnoreRobots crawls URLs regardless of the robots.txt of their host, e.g. for sites we own
+	IgnoreRobots bool
+	// UserAgent and Headers replace the defaults of the HTTP client when fetching pages
+	UserAgent string
+	Headers   map[string]string
+	// KeywordOptions holds how keywords are matched, keywords without options are matched as case-sensitive substrings
+	KeywordOptions map[string]extractor.KeywordOptions
+	// MaxKeywordMatches is the number of matches of every keyword returned with their snippet and position, zero returns none
+	MaxKeywordMatches int
+	// Language is the language stemmed keywords are analyzed in, it is detected from every page when empty
+	Language string
+	// OnScheduled is called with every URL queued for crawling, seeds included, calls are never concurrent
+	OnScheduled func(url string)
+	// OnSuccess is called with every success result as soon as it is produced, calls are never concurrent
+	OnSuccess func(SuccessCrawlResult)
+	// OnError is called with every error result as soon as it is produced, calls are never concurrent
+	OnError func(ErrorCrawlResult)
+}
+
 type SuccessCrawlResult struct {
-	URL              string
+	URL string
+	// FinalURL is the URL the page was served from once redirects were followed
+	FinalURL       string
+	Redirects      []Redirect
+	HTTPSDowngrade bool
+	// Attempts is the number of attempts at fetching the page from the origin, zero when served from cache
+	Attempts int
+	// AttemptErrors holds the errors of the failed attempts before the successful one
+	AttemptErrors []string
+	// BytesRead is the size of the page read from the origin, zero when served from cache
+	BytesRead int64
+	// Charset is the encoding the page was served in, it is always transcoded to UTF-8
+	Charset          string
 	Title            string
 	MetaDescriptions []string
-	Links            []string
+	Links            []Link
 	KeywordCounts    map[string]int
+	// KeywordResults holds the count of every keyword along with how it was matched
+	KeywordResults map[string]KeywordResult
+	// WordCount is the number of words of the visible text of the body
+	WordCount int
+	Source    string
+	// CacheAge is the age of the cached document used, nil when the document came from the origin
+	CacheAge       *time.Duration
+	Depth          int
+	DiscoveredFrom string
+	// LinkChecks follows the order of Links, it is nil unless CheckLinks is set
+	LinkChecks []LinkCheck
 }
 
-type ErrorCrawlResult struct {
+// KeywordResult is the count of a keyword by region, Error is set when matching was cut short, e.g. by a slow regular expression
+type KeywordResult struct {
+	// Count is the number of occurrences in the visible text of the body, Density its percentage of WordCount
+	Count           int
+	Regions         KeywordRegions
+	Density         float64
+	Mode            string
+	CaseInsensitive bool
+	FoldDiacritics  bool
+	// Language is the language a stemmed keyword was analyzed in, empty for other modes
+	Language string
+	// Matches holds the first matches in the visible text of the body, nil unless MaxKeywordMatches is set
+	Matches []KeywordMatch
+	Error   string
+}
+
+// KeywordMatch is where a keyword appears, Offset and Length are in characters of the visible text of the body
+// and Path is the CSS path of the element holding it
+type KeywordMatch struct {
+	Offset  int
+	Length  int
+	Snippet string
+	Path    string
+}
+
+// KeywordRegions counts the occurrences of a keyword by region of the page, regions overlap
+type KeywordRegions struct {
+	Title           int
+	MetaDescription int
+	H1              int
+	H2              int
+	H3              int
+	H4              int
+	H5              int
+	H6              int
+	Body            int
+	AnchorText      int
+	ImageAlt        int
+	URLPath         int
+}
+
+// Redirect is a hop of a redirect chain, Location is the raw Location header
+type Redirect struct {
+	URL        string
+	StatusCode int
+	Location   string
+	// Downgrade is set when the redirect goes from https to http
+	Downgrade bool
+}
+
+// Link is a resolved link found on a page, Class is one of internal, subdomain or external
+type Link struct {
 	URL   string
-	Error string
+	Text  string
+	Rel   []string
+	Class string
+}
+
+// LinkCheck is the outcome of checking a link, redirects aren't followed
+type LinkCheck struct {
+	URL         string
+	Method      string
+	StatusCode  int
+	RedirectURL string
+	Latency     time.Duration
+	ErrorClass  string
+	Error       string
+	Broken      bool
+}
+
+// LinkCheckSummary aggregates the link checks of a crawl, links found on several pages are counted once
+type LinkCheckSummary struct {
+	Checked    int
+	OK         int
+	Redirected int
+	Broken     int
+	// ErrorClasses counts the links which couldn't be reached by error class
+	ErrorClasses map[string]int
+	BrokenLinks  []BrokenLink
+}
+
+type BrokenLink struct {
+	URL        string
+	StatusCode int
+	ErrorClass string
+	// FoundOn lists the pages linking to it
+	FoundOn []string
+}
+
+// ErrorReason classifies error results that aren't plain extraction failures
+type ErrorReason string
+
+const (
+	// ErrorReasonCancelled is used for URLs left unprocessed because the crawl was cancelled
+	ErrorReasonCancelled ErrorReason = "cancelled"
+	// ErrorReasonRedirectLoop is used when a redirect points back to a URL already visited
+	ErrorReasonRedirectLoop ErrorReason = "redirect_loop"
+	// ErrorReasonTooManyRedirects is used when the redirect chain is longer than allowed
+	ErrorReasonTooManyRedirects ErrorReason = "too_many_redirects"
+	// ErrorReasonRedirectNotFollowed is used when the page redirects and redirects aren't followed
+	ErrorReasonRedirectNotFollowed ErrorReason = "redirect_not_followed"
+	// ErrorReasonRobotsDisallowed is used for URLs skipped because the robots.txt of their host disallows them
+	ErrorReasonRobotsDisallowed ErrorReason = "robots_disallowed"
+	// ErrorReasonCircuitOpen is used for URLs failed fast because their host kept failing
+	ErrorReasonCircuitOpen ErrorReason = "circuit_open"
+	// ErrorReasonBodyTooLarge is used for pages larger than the body size limit
+	ErrorReasonBodyTooLarge ErrorReason = "body_too_large"
+	// ErrorReasonUnsupportedContentType is used for pages which aren't HTML
+	ErrorReasonUnsupportedContentType ErrorReason = "unsupported_content_type"
+	// ErrorReasonBlocked is used for URLs the URL policy forbids fetching, e.g. internal addresses
+	ErrorReasonBlocked ErrorReason = "blocked"
+)
+
+type ErrorCrawlResult struct {
+	URL    string
+	Error  string
+	Reason ErrorReason
+	// Redirects holds the hops followed before a redirect error
+	Redirects []Redirect
+	// Attempts is the number of attempts at fetching the page, AttemptErrors their errors
+	Attempts      int
+	AttemptErrors []string
+	// BytesRead is the size of the body read from the origin before the page was rejected, e.g. for being too large
+	BytesRead int64
+	// ContentType is the media type of pages rejected because they aren't HTML
+	ContentType    string
+	Depth          int
+	DiscoveredFrom string
+}
+
+type JobState string
+
+const (
+	JobStateQueued    JobState = "queued"
+	JobStateRunning   JobState = "running"
+	JobStateDone      JobState = "done"
+	JobStateFailed    JobState = "failed"
+	JobStateCancelled JobState = "cancelled"
+)
+
+type JobProgress struct {
+	// Scheduled is the number of URLs queued so far, it grows as recursive crawls discover pages
+	Scheduled int
+	Completed int
+	Succeeded int
+	Failed    int
+}
+
+type Job struct {
+	ID              string
+	State           JobState
+	Progress        JobProgress
+	Error           string
+	UnprocessedURLs []string
+	CreatedAt       time.Time
+	StartedAt       time.Time
+	FinishedAt      time.Time
+}
+
+type JobResults struct {
+	Job                 Job
+	SuccessCrawlResults []SuccessCrawlResult
+	ErrorCrawlResults   []ErrorCrawlResult
 }
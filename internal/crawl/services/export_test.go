@@ -0,0 +1,19 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+// Acquire exposes acquire to the tests of the scheduler
+func (s *hostScheduler) Acquire(ctx context.Context, host string, crawlDelay time.Duration) (func(), error) {
+	return s.acquire(ctx, host, crawlDelay)
+}
+
+// TrackedHosts returns the number of hosts the scheduler keeps a queue for
+func (s *hostScheduler) TrackedHosts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.hosts)
+}
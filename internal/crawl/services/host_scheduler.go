@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/metrics"
+)
+
+// hostQueue tracks the pages of a host waiting for or holding one of its slots
+type hostQueue struct {
+	slots chan struct{}
+	// next is the earliest time the next request to the host may start
+	next time.Time
+	// waiting is the number of pages waiting for a slot or for the request delay
+	waiting int
+	// active is the number of pages waiting for or holding a slot
+	active int
+}
+
+type hostScheduler struct {
+	concurrentLimit int
+	delay           time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostQueue
+}
+
+// NewHostScheduler returns a scheduler allowing concurrentLimit requests at a time per host, across every crawl,
+// each starting at least delay after the previous one. A robots.txt Crawl-delay replaces delay for its host.
+func NewHostScheduler(concurrentLimit int, delay time.Duration) *hostScheduler {
+	return &hostScheduler{
+		concurrentLimit: concurrentLimit,
+		delay:           delay,
+		hosts:           map[string]*hostQueue{},
+	}
+}
+
+// acquire blocks until a request to the host may start, the returned release must be called once it is done
+func (s *hostScheduler) acquire(ctx context.Context, host string, crawlDelay time.Duration) (func(), error) {
+	s.mu.Lock()
+	queue, exists := s.hosts[host]
+	if !exists {
+		queue = &hostQueue{slots: make(chan struct{}, s.concurrentLimit)}
+		s.hosts[host] = queue
+	}
+	queue.active++
+	s.setWaiting(host, queue, 1)
+	s.mu.Unlock()
+
+	select {
+	case queue.slots <- struct{}{}:
+	case <-ctx.Done():
+		s.mu.Lock()
+		s.setWaiting(host, queue, -1)
+		s.deactivate(host, queue)
+		s.mu.Unlock()
+		return nil, ctx.Err()
+	}
+
+	// Reserve the next start time of the host
+	delay := s.delay
+	if crawlDelay > 0 {
+		delay = crawlDelay
+	}
+
+	s.mu.Lock()
+	at := queue.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	queue.next = at.Add(delay)
+	s.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	s.setWaiting(host, queue, -1)
+	s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		s.release(host, queue)
+		return nil, err
+	}
+
+	return func() { s.release(host, queue) }, nil
+}
+
+func (s *hostScheduler) release(host string, queue *hostQueue) {
+	<-queue.slots
+
+	s.mu.Lock()
+	s.deactivate(host, queue)
+	s.mu.Unlock()
+}
+
+// deactivate forgets idle hosts once their request delay is over, it must be called with mu held
+func (s *hostScheduler) deactivate(host string, queue *hostQueue) {
+	queue.active--
+	if queue.active > 0 {
+		return
+	}
+
+	wait := time.Until(queue.next)
+	if wait <= 0 {
+		delete(s.hosts, host)
+		return
+	}
+
+	// The next start time must be kept until it passes, the host is forgotten then unless it was used again
+	time.AfterFunc(wait, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.hosts[host] == queue && queue.active == 0 && !time.Now().Before(queue.next) {
+			delete(s.hosts, host)
+		}
+	})
+}
+
+// setWaiting updates the number of waiting pages of the host and its metric, it must be called with mu held
+func (s *hostScheduler) setWaiting(host string, queue *hostQueue, delta int) {
+	queue.waiting += delta
+	if queue.waiting == 0 {
+		metrics.CrawlHostQueueDepth.DeleteLabelValues(host)
+		return
+	}
+
+	metrics.CrawlHostQueueDepth.WithLabelValues(host).Set(float64(queue.waiting))
+}
@@ -0,0 +1,45 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/crawl/services"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostScheduler_ForgetsIdleHostsOnceTheirDelayIsOver(t *testing.T) {
+	scheduler := services.NewHostScheduler(2, 50*time.Millisecond)
+
+	for _, host := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		release, err := scheduler.Acquire(context.Background(), host, 0)
+		require.NoError(t, err)
+		release()
+	}
+
+	// The hosts are kept until their next request may start so the delay still applies to them
+	require.Equal(t, 3, scheduler.TrackedHosts())
+
+	require.Eventually(t, func() bool {
+		return scheduler.TrackedHosts() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHostScheduler_KeepsHostsUsedAgainWithinTheirDelay(t *testing.T) {
+	scheduler := services.NewHostScheduler(1, 50*time.Millisecond)
+
+	release, err := scheduler.Acquire(context.Background(), "example.com", 0)
+	require.NoError(t, err)
+	release()
+
+	// The second request waits for the delay, the host must still be tracked when the first one's expires
+	release, err = scheduler.Acquire(context.Background(), "example.com", 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, scheduler.TrackedHosts())
+
+	release()
+	require.Eventually(t, func() bool {
+		return scheduler.TrackedHosts() == 0
+	}, time.Second, 10*time.Millisecond)
+}
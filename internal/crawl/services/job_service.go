@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobFinished = errors.New("job already finished")
+)
+
+type crawler interface {
+	Crawl(ctx context.Context, urls []string, keywords []string, opts CrawlOptions) ([]SuccessCrawlResult, []ErrorCrawlResult, error)
+}
+
+// job is the mutable state of a submitted crawl job, guarded by jobService.mu
+type job struct {
+	Job
+	successCrawlResults []SuccessCrawlResult
+	errorCrawlResults   []ErrorCrawlResult
+	cancel              context.CancelFunc
+	done                chan struct{}
+}
+
+type jobService struct {
+	crawler   crawler
+	slots     chan struct{}
+	retention time.Duration
+	mu        sync.RWMutex
+	jobs      map[string]*job
+	logger    zerolog.Logger
+}
+
+// NewJobService returns a service running crawl jobs in the background.
+// At most concurrentLimit jobs run at the same time, the rest stay queued.
+// Finished jobs are forgotten once they are older than retention.
+func NewJobService(crawler crawler, concurrentLimit int, retention time.Duration) *jobService {
+	return &jobService{
+		crawler:   crawler,
+		slots:     make(chan struct{}, concurrentLimit),
+		retention: retention,
+		jobs:      make(map[string]*job),
+		logger:    log.With().Str("package", "services").Str("service", "JobService").Logger(),
+	}
+}
+
+// Submit queues a crawl job and returns immediately
+func (s *jobService) Submit(urls []string, keywords []string, opts CrawlOptions) (Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to generate job id: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		Job: Job{
+			ID:              id,
+			State:           JobStateQueued,
+			UnprocessedURLs: []string{},
+			CreatedAt:       time.Now(),
+		},
+		successCrawlResults: []SuccessCrawlResult{},
+		errorCrawlResults:   []ErrorCrawlResult{},
+		cancel:              cancel,
+		done:                make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	s.pruneExpiredJobs()
+	s.jobs[id] = j
+	queued := j.snapshot()
+	s.mu.Unlock()
+
+	s.logger.Info().Str("job_id", id).Int("urls", len(urls)).Msg("Job queued")
+	go s.run(ctx, j, urls, keywords, opts)
+
+	return queued, nil
+}
+
+// Get returns the current state of a job
+func (s *jobService) Get(id string) (Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+
+	return j.snapshot(), nil
+}
+
+// Cancel stops a queued or running job and waits until its in-flight fetches are aborted
+func (s *jobService) Cancel(ctx context.Context, id string) (Job, error) {
+	s.mu.RLock()
+	j, ok := s.jobs[id]
+	s.mu.RUnlock()
+
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+
+	select {
+	case <-j.done:
+		return Job{}, ErrJobFinished
+	default:
+	}
+
+	s.logger.Info().Str("job_id", id).Msg("Cancelling job")
+	j.cancel()
+
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+
+	return s.Get(id)
+}
+
+// Results returns the results produced by a job so far
+func (s *jobService) Results(id string) (JobResults, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return JobResults{}, ErrJobNotFound
+	}
+
+	return JobResults{
+		Job:                 j.snapshot(),
+		SuccessCrawlResults: append([]SuccessCrawlResult{}, j.successCrawlResults...),
+		ErrorCrawlResults:   append([]ErrorCrawlResult{}, j.errorCrawlResults...),
+	}, nil
+}
+
+func (s *jobService) run(ctx context.Context, j *job, urls []string, keywords []string, opts CrawlOptions) {
+	defer close(j.done)
+	defer j.cancel()
+
+	// Wait for a free slot unless the job is cancelled while queued
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.logger.Info().Str("job_id", j.ID).Msg("Job cancelled while queued")
+		j.State = JobStateCancelled
+		j.FinishedAt = time.Now()
+		j.UnprocessedURLs = append(j.UnprocessedURLs, urls...)
+		return
+	}
+
+	s.mu.Lock()
+	j.State = JobStateRunning
+	j.StartedAt = time.Now()
+	s.mu.Unlock()
+
+	s.logger.Info().Str("job_id", j.ID).Msg("Job started")
+
+	// Record results as they are produced so progress and partial results can be polled
+	opts.OnScheduled = func(url string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		j.Progress.Scheduled++
+	}
+	opts.OnSuccess = func(result SuccessCrawlResult) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		j.successCrawlResults = append(j.successCrawlResults, result)
+		j.Progress.Completed++
+		j.Progress.Succeeded++
+	}
+	opts.OnError = func(result ErrorCrawlResult) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		j.errorCrawlResults = append(j.errorCrawlResults, result)
+		if result.Reason == ErrorReasonCancelled {
+			j.UnprocessedURLs = append(j.UnprocessedURLs, result.URL)
+			return
+		}
+
+		j.Progress.Completed++
+		j.Progress.Failed++
+	}
+
+	_, _, err := s.crawler.Crawl(ctx, urls, keywords, opts)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j.FinishedAt = time.Now()
+	if ctx.Err() != nil {
+		s.logger.Info().Str("job_id", j.ID).Int("unprocessed", len(j.UnprocessedURLs)).Msg("Job cancelled")
+		j.State = JobStateCancelled
+		return
+	}
+
+	if err != nil {
+		s.logger.Error().Err(err).Str("job_id", j.ID).Msg("Job failed")
+		j.State = JobStateFailed
+		j.Error = err.Error()
+		return
+	}
+
+	s.logger.Info().Str("job_id", j.ID).Msg("Job done")
+	j.State = JobStateDone
+}
+
+// snapshot returns a copy of the job which is safe to use without holding jobService.mu
+func (j *job) snapshot() Job {
+	snapshot := j.Job
+	snapshot.UnprocessedURLs = append([]string{}, j.UnprocessedURLs...)
+	return snapshot
+}
+
+// pruneExpiredJobs removes finished jobs older than the retention period, the caller must hold s.mu
+func (s *jobService) pruneExpiredJobs() {
+	for id, j := range s.jobs {
+		if !j.FinishedAt.IsZero() && time.Since(j.FinishedAt) > s.retention {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
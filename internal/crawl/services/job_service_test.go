@@ -0,0 +1,218 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/crawl/services"
+	"github.com/stretchr/testify/require"
+)
+
+// Mocks
+type mockCrawler struct {
+	crawlFn func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error)
+}
+
+func (m *mockCrawler) Crawl(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+	if m != nil && m.crawlFn != nil {
+		return m.crawlFn(ctx, urls, keywords, opts)
+	}
+
+	return []services.SuccessCrawlResult{}, []services.ErrorCrawlResult{}, nil
+}
+
+// waitForState polls the job until it reaches the given state
+func waitForState(t *testing.T, jobService interface {
+	Get(id string) (services.Job, error)
+}, id string, state services.JobState) services.Job {
+	t.Helper()
+
+	var job services.Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = jobService.Get(id)
+		require.NoError(t, err)
+		return job.State == state
+	}, time.Second, 5*time.Millisecond)
+
+	return job
+}
+
+// Tests
+
+func TestJobService_Submit(t *testing.T) {
+	tests := []struct {
+		name                        string
+		mockCrawler                 *mockCrawler
+		expectedState               services.JobState
+		expectedError               string
+		expectedProgress            services.JobProgress
+		expectedSuccessCrawlResults []services.SuccessCrawlResult
+		expectedErrorCrawlResults   []services.ErrorCrawlResult
+	}{
+		{
+			name: "marks job as done and records results",
+			mockCrawler: &mockCrawler{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					success := services.SuccessCrawlResult{URL: "http://example.com", Title: "Title"}
+					failure := services.ErrorCrawlResult{URL: "http://example.com/404", Error: "failed to extract data"}
+					opts.OnScheduled(success.URL)
+					opts.OnScheduled(failure.URL)
+					opts.OnSuccess(success)
+					opts.OnError(failure)
+					return []services.SuccessCrawlResult{success}, []services.ErrorCrawlResult{failure}, nil
+				},
+			},
+			expectedState:    services.JobStateDone,
+			expectedProgress: services.JobProgress{Scheduled: 2, Completed: 2, Succeeded: 1, Failed: 1},
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{
+				{URL: "http://example.com", Title: "Title"},
+			},
+			expectedErrorCrawlResults: []services.ErrorCrawlResult{
+				{URL: "http://example.com/404", Error: "failed to extract data"},
+			},
+		},
+		{
+			name: "marks job as failed when crawl returns an error",
+			mockCrawler: &mockCrawler{
+				crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+					return nil, nil, fmt.Errorf("crawl failed")
+				},
+			},
+			expectedState:               services.JobStateFailed,
+			expectedError:               "crawl failed",
+			expectedSuccessCrawlResults: []services.SuccessCrawlResult{},
+			expectedErrorCrawlResults:   []services.ErrorCrawlResult{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobService := services.NewJobService(tt.mockCrawler, 1, time.Hour)
+
+			job, err := jobService.Submit([]string{"http://example.com"}, []string{"keyword1"}, services.CrawlOptions{})
+			require.NoError(t, err)
+			require.NotEmpty(t, job.ID)
+			require.Equal(t, services.JobStateQueued, job.State)
+
+			job = waitForState(t, jobService, job.ID, tt.expectedState)
+			require.Equal(t, tt.expectedError, job.Error)
+			require.Equal(t, tt.expectedProgress, job.Progress)
+			require.False(t, job.StartedAt.IsZero())
+			require.False(t, job.FinishedAt.IsZero())
+
+			results, err := jobService.Results(job.ID)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedSuccessCrawlResults, results.SuccessCrawlResults)
+			require.Equal(t, tt.expectedErrorCrawlResults, results.ErrorCrawlResults)
+		})
+	}
+}
+
+func TestJobService_SubmitQueuesJobsOverLimit(t *testing.T) {
+	release := make(chan struct{})
+	crawler := &mockCrawler{
+		crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+			<-release
+			return []services.SuccessCrawlResult{}, []services.ErrorCrawlResult{}, nil
+		},
+	}
+	jobService := services.NewJobService(crawler, 1, time.Hour)
+
+	first, err := jobService.Submit([]string{"http://example.com"}, nil, services.CrawlOptions{})
+	require.NoError(t, err)
+	waitForState(t, jobService, first.ID, services.JobStateRunning)
+
+	second, err := jobService.Submit([]string{"http://example.com/2"}, nil, services.CrawlOptions{})
+	require.NoError(t, err)
+
+	// The second job stays queued while the first one holds the only slot
+	time.Sleep(20 * time.Millisecond)
+	job, err := jobService.Get(second.ID)
+	require.NoError(t, err)
+	require.Equal(t, services.JobStateQueued, job.State)
+
+	close(release)
+	waitForState(t, jobService, first.ID, services.JobStateDone)
+	waitForState(t, jobService, second.ID, services.JobStateDone)
+}
+
+func TestJobService_Get(t *testing.T) {
+	jobService := services.NewJobService(&mockCrawler{}, 1, time.Hour)
+
+	_, err := jobService.Get("unknown")
+	require.ErrorIs(t, err, services.ErrJobNotFound)
+
+	_, err = jobService.Results("unknown")
+	require.ErrorIs(t, err, services.ErrJobNotFound)
+}
+
+func TestJobService_Cancel(t *testing.T) {
+	// blockingCrawler reports the first URL then blocks until the crawl is cancelled
+	blockingCrawler := &mockCrawler{
+		crawlFn: func(ctx context.Context, urls []string, keywords []string, opts services.CrawlOptions) ([]services.SuccessCrawlResult, []services.ErrorCrawlResult, error) {
+			for _, url := range urls {
+				opts.OnScheduled(url)
+			}
+			opts.OnSuccess(services.SuccessCrawlResult{URL: urls[0]})
+			<-ctx.Done()
+			for _, url := range urls[1:] {
+				opts.OnError(services.ErrorCrawlResult{URL: url, Error: ctx.Err().Error(), Reason: services.ErrorReasonCancelled})
+			}
+			return nil, nil, nil
+		},
+	}
+
+	t.Run("cancels running job and records unprocessed urls", func(t *testing.T) {
+		jobService := services.NewJobService(blockingCrawler, 1, time.Hour)
+
+		job, err := jobService.Submit([]string{"http://example.com", "http://example.com/a", "http://example.com/b"}, nil, services.CrawlOptions{})
+		require.NoError(t, err)
+		waitForState(t, jobService, job.ID, services.JobStateRunning)
+
+		job, err = jobService.Cancel(context.Background(), job.ID)
+		require.NoError(t, err)
+		require.Equal(t, services.JobStateCancelled, job.State)
+		require.Equal(t, services.JobProgress{Scheduled: 3, Completed: 1, Succeeded: 1}, job.Progress)
+		require.Equal(t, []string{"http://example.com/a", "http://example.com/b"}, job.UnprocessedURLs)
+	})
+
+	t.Run("cancels queued job", func(t *testing.T) {
+		jobService := services.NewJobService(blockingCrawler, 1, time.Hour)
+
+		running, err := jobService.Submit([]string{"http://example.com"}, nil, services.CrawlOptions{})
+		require.NoError(t, err)
+		waitForState(t, jobService, running.ID, services.JobStateRunning)
+
+		queued, err := jobService.Submit([]string{"http://example.com/queued"}, nil, services.CrawlOptions{})
+		require.NoError(t, err)
+
+		job, err := jobService.Cancel(context.Background(), queued.ID)
+		require.NoError(t, err)
+		require.Equal(t, services.JobStateCancelled, job.State)
+		require.Equal(t, []string{"http://example.com/queued"}, job.UnprocessedURLs)
+
+		_, err = jobService.Cancel(context.Background(), running.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("returns error when job already finished", func(t *testing.T) {
+		jobService := services.NewJobService(&mockCrawler{}, 1, time.Hour)
+
+		job, err := jobService.Submit([]string{"http://example.com"}, nil, services.CrawlOptions{})
+		require.NoError(t, err)
+		waitForState(t, jobService, job.ID, services.JobStateDone)
+
+		_, err = jobService.Cancel(context.Background(), job.ID)
+		require.ErrorIs(t, err, services.ErrJobFinished)
+	})
+
+	t.Run("returns error when job doesn't exist", func(t *testing.T) {
+		jobService := services.NewJobService(&mockCrawler{}, 1, time.Hour)
+
+		_, err := jobService.Cancel(context.Background(), "unknown")
+		require.ErrorIs(t, err, services.ErrJobNotFound)
+	})
+}
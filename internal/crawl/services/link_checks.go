@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/jponc/domain-crawler/internal/linkchecker"
+)
+
+// linkCheckCall is a link check shared by every page linking to the same URL
+type linkCheckCall struct {
+	done   chan struct{}
+	result linkchecker.Result
+}
+
+// linkCheckMemo makes sure links found on several pages are only checked once per crawl
+type linkCheckMemo struct {
+	linkChecker linkChecker
+	mu          sync.Mutex
+	calls       map[string]*linkCheckCall
+}
+
+func newLinkCheckMemo(linkChecker linkChecker) *linkCheckMemo {
+	return &linkCheckMemo{
+		linkChecker: linkChecker,
+		calls:       make(map[string]*linkCheckCall),
+	}
+}
+
+// check returns the results of urls in the same order, checking the ones no other page checked yet
+func (m *linkCheckMemo) check(ctx context.Context, urls []string) []LinkCheck {
+	calls := make([]*linkCheckCall, len(urls))
+	owned := []*linkCheckCall{}
+	ownedURLs := []string{}
+
+	m.mu.Lock()
+	for i, url := range urls {
+		call, ok := m.calls[url]
+		if !ok {
+			call = &linkCheckCall{done: make(chan struct{})}
+			m.calls[url] = call
+			owned = append(owned, call)
+			ownedURLs = append(ownedURLs, url)
+		}
+		calls[i] = call
+	}
+	m.mu.Unlock()
+
+	if len(owned) > 0 {
+		results := m.linkChecker.Check(ctx, ownedURLs)
+		for i, call := range owned {
+			call.result = results[i]
+			close(call.done)
+		}
+	}
+
+	linkChecks := make([]LinkCheck, 0, len(urls))
+	for _, call := range calls {
+		// Results of other pages are always delivered, the checker gives up on its own when the crawl is cancelled
+		<-call.done
+		linkChecks = append(linkChecks, convertLinkCheckResult(call.result))
+	}
+
+	return linkChecks
+}
+
+func convertLinkCheckResult(result linkchecker.Result) LinkCheck {
+	return LinkCheck{
+		URL:         result.URL,
+		Method:      result.Method,
+		StatusCode:  result.StatusCode,
+		RedirectURL: result.RedirectURL,
+		Latency:     result.Latency,
+		ErrorClass:  string(result.ErrorClass),
+		Error:       result.Error,
+		Broken:      result.Broken(),
+	}
+}
+
+// SummarizeLinkChecks aggregates the link checks of every page, counting each link once.
+// It returns false when links weren't checked.
+func SummarizeLinkChecks(results []SuccessCrawlResult) (LinkCheckSummary, bool) {
+	summary := LinkCheckSummary{
+		ErrorClasses: map[string]int{},
+		BrokenLinks:  []BrokenLink{},
+	}
+
+	checked := false
+	brokenLinks := map[string]*BrokenLink{}
+	seen := map[string]bool{}
+	for _, result := range results {
+		if result.LinkChecks == nil {
+			continue
+		}
+		checked = true
+
+		for _, linkCheck := range result.LinkChecks {
+			if brokenLink, ok := brokenLinks[linkCheck.URL]; ok {
+				brokenLink.FoundOn = append(brokenLink.FoundOn, result.URL)
+			}
+
+			if seen[linkCheck.URL] {
+				continue
+			}
+			seen[linkCheck.URL] = true
+
+			summary.Checked++
+			switch {
+			case linkCheck.Broken:
+				summary.Broken++
+				brokenLinks[linkCheck.URL] = &BrokenLink{
+					URL:        linkCheck.URL,
+					StatusCode: linkCheck.StatusCode,
+					ErrorClass: linkCheck.ErrorClass,
+					FoundOn:    []string{result.URL},
+				}
+			case linkCheck.StatusCode >= 300:
+				summary.Redirected++
+			default:
+				summary.OK++
+			}
+
+			if linkCheck.ErrorClass != "" {
+				summary.ErrorClasses[linkCheck.ErrorClass]++
+			}
+		}
+	}
+
+	for _, brokenLink := range brokenLinks {
+		sort.Strings(brokenLink.FoundOn)
+		summary.BrokenLinks = append(summary.BrokenLinks, *brokenLink)
+	}
+	sort.Slice(summary.BrokenLinks, func(i, j int) bool {
+		return summary.BrokenLinks[i].URL < summary.BrokenLinks[j].URL
+	})
+
+	return summary, checked
+}
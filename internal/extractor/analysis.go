@@ -0,0 +1,209 @@
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	snowballstem "github.com/blevesearch/snowballstem"
+	"github.com/blevesearch/snowballstem/dutch"
+	"github.com/blevesearch/snowballstem/english"
+	"github.com/blevesearch/snowballstem/french"
+	"github.com/blevesearch/snowballstem/german"
+	"github.com/blevesearch/snowballstem/italian"
+	"github.com/blevesearch/snowballstem/portuguese"
+	"github.com/blevesearch/snowballstem/spanish"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// DefaultLanguage is used when the language of a page can't be told
+	DefaultLanguage = "en"
+	// detectionBytes is how much of the text of a page is looked at to detect its language
+	detectionBytes = 64 << 10
+)
+
+// ErrUnsupportedLanguage is returned when stemming is asked for in a language without a stemmer
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+// language is how text of a language is analyzed
+type language struct {
+	stem      func(env *snowballstem.Env) bool
+	stopwords map[string]bool
+}
+
+var languages = map[string]language{
+	"de": {stem: german.Stem, stopwords: germanStopwords},
+	"en": {stem: english.Stem, stopwords: englishStopwords},
+	"es": {stem: spanish.Stem, stopwords: spanishStopwords},
+	"fr": {stem: french.Stem, stopwords: frenchStopwords},
+	"it": {stem: italian.Stem, stopwords: italianStopwords},
+	"nl": {stem: dutch.Stem, stopwords: dutchStopwords},
+	"pt": {stem: portuguese.Stem, stopwords: portugueseStopwords},
+}
+
+// Languages returns the ISO 639-1 codes of the languages keywords can be stemmed in, sorted
+func Languages() []string {
+	codes := make([]string, 0, len(languages))
+	for code := range languages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+// token is an analyzed word along with its bounds in the analyzed text
+type token struct {
+	term  string
+	start int
+	end   int
+}
+
+// analysisKey identifies the analysis of a text, the same regions are analyzed for every stemmed keyword
+type analysisKey struct {
+	text           string
+	foldDiacritics bool
+}
+
+// analyzer splits text into words, lowercases them, drops stopwords and stems the others. It isn't safe for
+// concurrent use since it keeps the tokens of the texts it analyzed.
+type analyzer struct {
+	code     string
+	language language
+	tokens   map[analysisKey][]token
+}
+
+func newAnalyzer(code string) (*analyzer, error) {
+	lang, exists := languages[code]
+	if !exists {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedLanguage, code)
+	}
+
+	return &analyzer{
+		code:     code,
+		language: lang,
+		tokens:   map[analysisKey][]token{},
+	}, nil
+}
+
+// analyze returns the tokens of text, words being runs of Unicode letters, marks, numbers and connectors.
+// Diacritics are folded after stemming since stemmers rely on them.
+func (a *analyzer) analyze(text string, foldDiacritics bool) []token {
+	key := analysisKey{text: text, foldDiacritics: foldDiacritics}
+	if tokens, exists := a.tokens[key]; exists {
+		return tokens
+	}
+
+	tokens := []token{}
+	eachWord(text, func(start, end int) {
+		word := strings.ToLower(norm.NFC.String(text[start:end]))
+		if a.language.stopwords[word] {
+			return
+		}
+
+		env := snowballstem.NewEnv(word)
+		a.language.stem(env)
+		term := env.Current()
+		if foldDiacritics {
+			term = normalizeText(term, true, false)
+		}
+
+		tokens = append(tokens, token{term: term, start: start, end: end})
+	})
+
+	a.tokens[key] = tokens
+	return tokens
+}
+
+// eachWord calls fn with the bounds of every word of text, apostrophes split words so l'amour is l and amour
+func eachWord(text string, fn func(start, end int)) {
+	start := -1
+	for i, r := range text {
+		switch {
+		case isWordRune(r) && start < 0:
+			start = i
+		case !isWordRune(r) && start >= 0:
+			fn(start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		fn(start, len(text))
+	}
+}
+
+// detectLanguage returns the supported language of the page from its <html lang> attribute, or else from
+// the stopwords found in its text, DefaultLanguage when neither tells
+func detectLanguage(doc *goquery.Document, text string) string {
+	lang := strings.ToLower(strings.TrimSpace(doc.Find("html").AttrOr("lang", "")))
+	if primary, _, _ := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-"); primary != "" {
+		if _, exists := languages[primary]; exists {
+			return primary
+		}
+	}
+
+	if len(text) > detectionBytes {
+		text = text[:detectionBytes]
+	}
+
+	hits := map[string]int{}
+	eachWord(text, func(start, end int) {
+		word := strings.ToLower(text[start:end])
+		for code, lang := range languages {
+			if lang.stopwords[word] {
+				hits[code]++
+			}
+		}
+	})
+
+	detected, best := DefaultLanguage, 0
+	for _, code := range Languages() {
+		if hits[code] > best {
+			detected, best = code, hits[code]
+		}
+	}
+
+	return detected
+}
+
+// usesStemming tells whether any of the keywords is stemmed, the language of the page is only needed then
+func usesStemming(keywords []string, keywordOptions map[string]KeywordOptions) bool {
+	for _, keyword := range keywords {
+		if keywordOptions[keyword].Mode == KeywordModeStemmed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findTerms returns the bounds of the first n non-overlapping runs of tokens matching terms, every run when n is negative
+func findTerms(tokens []token, terms []string, n int) [][]int {
+	matches := [][]int{}
+	if len(terms) == 0 {
+		return matches
+	}
+
+	for i := 0; i+len(terms) <= len(tokens) && len(matches) != n; {
+		matched := true
+		for j, term := range terms {
+			if tokens[i+j].term != term {
+				matched = false
+				break
+			}
+		}
+
+		if !matched {
+			i++
+			continue
+		}
+
+		matches = append(matches, []int{tokens[i].start, tokens[i+len(terms)-1].end})
+		i += len(terms)
+	}
+
+	return matches
+}
@@ -0,0 +1,90 @@
+package extractor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// sniffLen is how much of the body http.DetectContentType looks at
+const sniffLen = 512
+
+var (
+	// ErrBodyTooLarge is returned when the body of a page is larger than the configured limit
+	ErrBodyTooLarge = errors.New("response body too large")
+	// ErrUnsupportedContentType is returned for responses which aren't HTML, they are never parsed
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+)
+
+// ContentTypeError is returned when the response isn't HTML, ContentType is the declared or sniffed media type
+type ContentTypeError struct {
+	ContentType string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrUnsupportedContentType, e.ContentType)
+}
+
+func (e *ContentTypeError) Unwrap() error {
+	return ErrUnsupportedContentType
+}
+
+// readHTMLBody reads the body when it is HTML and no larger than maxBytes, zero meaning no limit.
+// It returns the number of bytes read, even when failing.
+func readHTMLBody(r io.Reader, header http.Header, contentLength int64, maxBytes int64) ([]byte, int64, error) {
+	// Don't download what is already known to be too large
+	if maxBytes > 0 && contentLength > maxBytes {
+		return nil, 0, fmt.Errorf("%w: %d bytes over the limit of %d bytes", ErrBodyTooLarge, contentLength, maxBytes)
+	}
+
+	body := bufio.NewReaderSize(r, sniffLen)
+	head, err := body.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, int64(len(head)), fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if contentType, ok := htmlContentType(header.Get("Content-Type"), head); !ok {
+		return nil, int64(len(head)), &ContentTypeError{ContentType: contentType}
+	}
+
+	var reader io.Reader = body
+	if maxBytes > 0 {
+		// Read one more byte than allowed to tell bodies of exactly maxBytes from larger ones
+		reader = io.LimitReader(body, maxBytes+1)
+	}
+
+	var buf bytes.Buffer
+	read, err := io.Copy(&buf, reader)
+	if err != nil {
+		return nil, read, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if maxBytes > 0 && read > maxBytes {
+		return nil, read, fmt.Errorf("%w: over the limit of %d bytes", ErrBodyTooLarge, maxBytes)
+	}
+
+	return buf.Bytes(), read, nil
+}
+
+// htmlContentType tells whether the response is HTML from its Content-Type header and the start of its body.
+// Bodies which are obviously binary, e.g. an image served as text/html, are rejected whatever their Content-Type.
+// Responses without a meaningful Content-Type are accepted as long as they sniff as text, since the sniffer
+// only recognises HTML starting with a handful of tags.
+func htmlContentType(header string, head []byte) (string, bool) {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !strings.HasPrefix(sniffed, "text/") {
+		return sniffed, false
+	}
+
+	declared, _, err := mime.ParseMediaType(header)
+	if err != nil || declared == "application/octet-stream" {
+		return sniffed, true
+	}
+
+	return declared, declared == "text/html" || declared == "application/xhtml+xml"
+}
@@ -0,0 +1,240 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/metrics"
+	"github.com/jponc/domain-crawler/internal/urlpolicy"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrCircuitOpen is returned without contacting the host while its circuit is open
+var ErrCircuitOpen = errors.New("circuit open")
+
+// BreakerPolicy controls when the circuit of a host opens, the zero value never opens it
+type BreakerPolicy struct {
+	// FailureThreshold is the number of consecutive failed attempts opening the circuit
+	FailureThreshold int
+	// OpenDuration is how long the circuit stays open before a probe is let through
+	OpenDuration time.Duration
+}
+
+// BreakerState is the state of the circuit of a host
+type BreakerState string
+
+const (
+	// BreakerClosed lets every request through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen fails every request fast
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe through, its outcome closes or opens the circuit again
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// HostBreaker is a snapshot of the circuit of a host
+type HostBreaker struct {
+	Host                string
+	State               BreakerState
+	ConsecutiveFailures int
+	// OpenedAt and RetryAt are zero while the circuit is closed, RetryAt is when the next probe may go through
+	OpenedAt time.Time
+	RetryAt  time.Time
+}
+
+// breakerOutcome is how an attempt counts toward the circuit of its host
+type breakerOutcome int
+
+const (
+	outcomeSuccess breakerOutcome = iota
+	outcomeFailure
+	// outcomeIgnored is an attempt which didn't tell anything about the host, e.g. cancelled by the caller
+	outcomeIgnored
+)
+
+// hostCircuit is the circuit of a host, probing is set while the half-open probe runs
+type hostCircuit struct {
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+type circuitBreaker struct {
+	policy BreakerPolicy
+
+	mu    sync.Mutex
+	hosts map[string]*hostCircuit
+
+	logger zerolog.Logger
+}
+
+// NewCircuitBreaker returns a breaker tracking the circuit of every host fetched by the extractor
+func NewCircuitBreaker(policy BreakerPolicy) *circuitBreaker {
+	return &circuitBreaker{
+		policy: policy,
+		hosts:  map[string]*hostCircuit{},
+		logger: log.With().Str("package", "extractor").Str("client", "CircuitBreaker").Logger(),
+	}
+}
+
+// allow returns ErrCircuitOpen when the host must not be contacted, otherwise done must be called with the outcome of the attempt
+func (b *circuitBreaker) allow(host string) (func(outcome breakerOutcome), error) {
+	if b.policy.FailureThreshold <= 0 {
+		return func(breakerOutcome) {}, nil
+	}
+	host = strings.ToLower(host)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	circuit, exists := b.hosts[host]
+	if !exists {
+		circuit = &hostCircuit{state: BreakerClosed}
+		b.hosts[host] = circuit
+	}
+
+	probe := false
+	switch circuit.state {
+	case BreakerOpen:
+		if time.Since(circuit.openedAt) < b.policy.OpenDuration {
+			metrics.ExtractorBreakerRejections.Inc()
+			return nil, fmt.Errorf("%w for host %s", ErrCircuitOpen, host)
+		}
+		b.setState(host, circuit, BreakerHalfOpen)
+		fallthrough
+	case BreakerHalfOpen:
+		// Only a single probe at a time, the others fail fast until it tells whether the host is back
+		if circuit.probing {
+			metrics.ExtractorBreakerRejections.Inc()
+			return nil, fmt.Errorf("%w for host %s", ErrCircuitOpen, host)
+		}
+		circuit.probing = true
+		probe = true
+	}
+
+	return func(outcome breakerOutcome) {
+		b.done(host, probe, outcome)
+	}, nil
+}
+
+func (b *circuitBreaker) done(host string, probe bool, outcome breakerOutcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// The circuit may have been closed and forgotten by a concurrent attempt since this one started
+	circuit, exists := b.hosts[host]
+	if !exists {
+		circuit = &hostCircuit{state: BreakerClosed}
+		b.hosts[host] = circuit
+	}
+
+	if probe {
+		circuit.probing = false
+	}
+
+	switch outcome {
+	case outcomeSuccess:
+		circuit.failures = 0
+		if circuit.state != BreakerClosed {
+			b.logger.Info().Str("host", host).Msg("Host is back, closing circuit")
+		}
+		b.setState(host, circuit, BreakerClosed)
+	case outcomeFailure:
+		circuit.failures++
+
+		// A failed probe opens the circuit again, so does reaching the threshold while closed
+		if probe || (circuit.state == BreakerClosed && circuit.failures >= b.policy.FailureThreshold) {
+			b.logger.Warn().Str("host", host).Int("failures", circuit.failures).Dur("open_duration", b.policy.OpenDuration).Msg("Opening circuit")
+			circuit.openedAt = time.Now()
+			b.setState(host, circuit, BreakerOpen)
+		}
+	}
+
+	// Healthy hosts aren't tracked so the map doesn't grow with every host ever fetched
+	if circuit.state == BreakerClosed && circuit.failures == 0 {
+		delete(b.hosts, host)
+	}
+}
+
+// setState moves the circuit to state and reports it, the caller must hold b.mu
+func (b *circuitBreaker) setState(host string, circuit *hostCircuit, state BreakerState) {
+	if circuit.state != state && state == BreakerOpen {
+		metrics.ExtractorBreakerOpened.Inc()
+	}
+	circuit.state = state
+
+	if state == BreakerClosed {
+		metrics.ExtractorBreakerState.DeleteLabelValues(host)
+		return
+	}
+	metrics.ExtractorBreakerState.WithLabelValues(host).Set(breakerStateValue(state))
+}
+
+// Hosts returns the circuit of every host which failed since it last succeeded, sorted by host
+func (b *circuitBreaker) Hosts() []HostBreaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hosts := []HostBreaker{}
+	for host, circuit := range b.hosts {
+		// Hosts are only tracked from their first attempt, those which haven't failed yet aren't worth listing
+		if circuit.state == BreakerClosed && circuit.failures == 0 {
+			continue
+		}
+
+		hb := HostBreaker{
+			Host:                host,
+			State:               circuit.state,
+			ConsecutiveFailures: circuit.failures,
+		}
+		if circuit.state != BreakerClosed {
+			hb.OpenedAt = circuit.openedAt
+			hb.RetryAt = circuit.openedAt.Add(b.policy.OpenDuration)
+		}
+		hosts = append(hosts, hb)
+	}
+
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].Host < hosts[j].Host
+	})
+
+	return hosts
+}
+
+// breakerOutcomeOf tells how an attempt counts toward the circuit, only transient failures of the host count as failures.
+// Blocked attempts never reached the host.
+func breakerOutcomeOf(ctx context.Context, err error) breakerOutcome {
+	if err == nil {
+		return outcomeSuccess
+	}
+
+	var retryable *retryableError
+	switch {
+	case ctx.Err() != nil, errors.Is(err, urlpolicy.ErrBlocked):
+		return outcomeIgnored
+	case errors.As(err, &retryable):
+		return outcomeFailure
+	}
+
+	// The host answered, e.g. with a 404 or a redirect we didn't follow
+	return outcomeSuccess
+}
+
+// breakerStateValue is the value of the state gauge, higher is worse
+func breakerStateValue(state BreakerState) float64 {
+	switch state {
+	case BreakerHalfOpen:
+		return 1
+	case BreakerOpen:
+		return 2
+	}
+
+	return 0
+}
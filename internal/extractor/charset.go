@@ -0,0 +1,33 @@
+package extractor
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+// defaultCharset is what the HTML spec falls back to for pages declaring no charset
+const defaultCharset = "windows-1252"
+
+// decodeHTML transcodes the body to UTF-8 and returns the name of the charset it was decoded from.
+// The charset comes from the BOM, the Content-Type header or a <meta> tag, in that order.
+func decodeHTML(body []byte, contentType string) (string, string, error) {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+
+	// The fallback is only picked from the start of the body, pages which are UTF-8 as a whole are left as is
+	if !certain && name == defaultCharset && utf8.Valid(body) {
+		return string(body), "utf-8", nil
+	}
+
+	if name == "utf-8" {
+		return string(body), name, nil
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return "", name, fmt.Errorf("failed to decode %s body: %w", name, err)
+	}
+
+	return string(decoded), name, nil
+}
@@ -2,12 +2,16 @@ package extractor
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/jponc/domain-crawler/internal/metrics"
+	"github.com/jponc/domain-crawler/internal/urlpolicy"
+	"github.com/jponc/domain-crawler/internal/utils"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -17,28 +21,57 @@ type cache interface {
 	Set(k, v string)
 }
 
+// document is a fetched HTML document along with where it came from
+type document struct {
+	html   string
+	source FetchSource
+	// storedAt is when the cached copy used was stored or last revalidated, zero for documents from the origin
+	storedAt  time.Time
+	finalURL  string
+	redirects []Redirect
+	// attempts is the number of attempts at fetching from the origin, attemptErrors the errors of the failed ones
+	attempts      int
+	attemptErrors []string
+	// bytesRead is the size of the body read from the origin, zero for cached documents
+	bytesRead int64
+	// charset is the encoding the page was served in, html is always UTF-8
+	charset string
+}
+
+// bypassKeyPrefix keeps fetches bypassing the cache from sharing fetches which write to it
+const bypassKeyPrefix = "bypass "
+
 type client struct {
 	httpClient  *http.Client
 	resultCache cache
-	logger      zerolog.Logger
+	retryPolicy RetryPolicy
+	breaker     *circuitBreaker
+	// maxBodyBytes is the largest body read from the origin, zero meaning no limit
+	maxBodyBytes int64
+	inflight     *coalescer
+	logger       zerolog.Logger
 }
 
-func NewExtractorClient(httpClient *http.Client, resultCache cache) *client {
+func NewExtractorClient(httpClient *http.Client, resultCache cache, retryPolicy RetryPolicy, breaker *circuitBreaker, maxBodyBytes int64) *client {
 	return &client{
-		httpClient:  httpClient,
-		resultCache: resultCache,
-		logger:      log.With().Str("package", "extractor").Str("client", "ExtractorClient").Logger(),
+		httpClient:   httpClient,
+		resultCache:  resultCache,
+		retryPolicy:  retryPolicy,
+		breaker:      breaker,
+		maxBodyBytes: maxBodyBytes,
+		inflight:     newCoalescer(),
+		logger:       log.With().Str("package", "extractor").Str("client", "ExtractorClient").Logger(),
 	}
 }
 
-func (c *client) Extract(ctx context.Context, url string, keywords []string) (*ExtractResult, error) {
-	html, err := c.fetchHTML(ctx, url)
+func (c *client) Extract(ctx context.Context, url string, keywords []string, opts Options) (*ExtractResult, error) {
+	fetched, err := c.fetchHTML(ctx, url, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch html: %w", err)
 	}
 
 	// Parse HTML doc
-	reader := strings.NewReader(html)
+	reader := strings.NewReader(fetched.html)
 	doc, err := goquery.NewDocumentFromReader(reader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse html: %w", err)
@@ -56,67 +89,265 @@ func (c *client) Extract(ctx context.Context, url string, keywords []string) (*E
 		}
 	})
 
-	// Extract links
-	links := []string{}
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
-		if href, exists := s.Attr("href"); exists {
-			links = append(links, href)
-		}
-	})
+	// Extract links, relative links are relative to where the document was served from
+	links := extractLinks(doc, fetched.finalURL, opts.StripTrackingParams)
 
-	// Extract keyword counts
-	keywordCounts := getKeywordCounts(doc, keywords)
+	// Match keywords by region of the page, counts of the visible body text are kept alongside the detailed results
+	finalURL := fetched.finalURL
+	if finalURL == "" {
+		finalURL = url
+	}
+	regions := getPageRegions(doc, finalURL, title, metaDescriptions)
+	wordCount := countWords(regions.body)
+	lang := opts.Language
+	if lang == "" && usesStemming(keywords, opts.KeywordOptions) {
+		lang = detectLanguage(doc, regions.body)
+	}
+	keywordResults := getKeywordResults(ctx, regions, wordCount, keywords, opts.KeywordOptions, min(opts.MaxKeywordMatches, MaxKeywordMatches), lang)
+	keywordCounts := KeywordCounts{}
+	for keyword, keywordResult := range keywordResults {
+		keywordCounts[keyword] = keywordResult.Count
+	}
 
 	result := ExtractResult{
 		URL:              url,
+		FinalURL:         fetched.finalURL,
+		Redirects:        fetched.redirects,
+		HTTPSDowngrade:   hasHTTPSDowngrade(fetched.redirects),
+		Attempts:         fetched.attempts,
+		AttemptErrors:    fetched.attemptErrors,
+		BytesRead:        fetched.bytesRead,
+		Charset:          fetched.charset,
 		Title:            title,
 		MetaDescriptions: metaDescriptions,
 		Links:            links,
 		KeywordCounts:    keywordCounts,
+		KeywordResults:   keywordResults,
+		WordCount:        wordCount,
+		Source:           fetched.source,
+	}
+
+	if fetched.source != SourceOrigin {
+		result.CacheAge = time.Since(fetched.storedAt)
 	}
 
 	// Return result
 	return &result, nil
 }
 
-func (c *client) fetchHTML(ctx context.Context, url string) (string, error) {
-	// Check cache if available
-	if cachedHTML, exists := c.resultCache.Get(url); exists {
+func (c *client) fetchHTML(ctx context.Context, url string, opts Options) (document, error) {
+	policy := opts.Cache
+	key := utils.NormalizeURL(url) + opts.Redirects.keySuffix() + opts.headersKeySuffix()
+	if policy.Mode == CacheModeBypass || opts.hasHeaderOverrides() {
+		return c.inflight.do(ctx, bypassKeyPrefix+key, func(ctx context.Context) (document, error) {
+			return c.fetchFromOrigin(ctx, url, opts, nil, false)
+		})
+	}
+
+	// Check cache if available, documents reached through more redirects than allowed are fetched again
+	cached := c.getCachedResponse(url)
+	if cached != nil && !opts.Redirects.allows(len(cached.Redirects)) {
+		cached = nil
+	}
+
+	if cached != nil && policy.Mode != CacheModeRefresh && c.isFreshEnough(cached, policy, time.Now()) {
 		c.logger.Info().Str("url", url).Msg("Returning cached HTML")
-		return cachedHTML, nil
+		return cached.document(url, SourceCache), nil
+	}
+
+	// Concurrent callers for the same URL share a single origin fetch
+	return c.inflight.do(ctx, key, func(ctx context.Context) (document, error) {
+		return c.fetchFromOrigin(ctx, url, opts, cached, true)
+	})
+}
+
+// isFreshEnough tells whether the cached document is fresh for the origin and young enough for the request
+func (c *client) isFreshEnough(cached *cachedResponse, policy CachePolicy, now time.Time) bool {
+	if !cached.isFresh(now) {
+		return false
+	}
+
+	return policy.MaxAge == nil || now.Sub(cached.StoredAt) <= *policy.MaxAge
+}
+
+// fetchFromOrigin downloads the document, or revalidates the stale cached document when there is one.
+// Network errors, 429 and 5xx responses are retried according to the retry policy.
+// The document is only cached when store is set.
+func (c *client) fetchFromOrigin(ctx context.Context, url string, opts Options, cached *cachedResponse, store bool) (document, error) {
+	attemptErrors := []string{}
+	for attempt := 1; ; attempt++ {
+		fetched, err := c.fetchOnce(ctx, url, opts, cached, store)
+		if err == nil {
+			fetched.attempts = attempt
+			if len(attemptErrors) > 0 {
+				fetched.attemptErrors = attemptErrors
+			}
+			return fetched, nil
+		}
+		attemptErrors = append(attemptErrors, err.Error())
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt >= c.retryPolicy.maxAttempts() {
+			return document{}, &AttemptsError{Errors: attemptErrors, Err: err, BytesRead: fetched.bytesRead}
+		}
+
+		// Honour the delay asked by the origin, unless it is longer than we are willing to wait
+		delay := c.retryPolicy.backoff(attempt)
+		if retryable.retryAfter > 0 {
+			if c.retryPolicy.MaxDelay > 0 && retryable.retryAfter > c.retryPolicy.MaxDelay {
+				return document{}, &AttemptsError{Errors: attemptErrors, Err: err, BytesRead: fetched.bytesRead}
+			}
+			delay = retryable.retryAfter
+		}
+
+		c.logger.Warn().Err(err).Str("url", url).Int("attempt", attempt).Dur("delay", delay).Msg("Retrying fetch from origin")
+		retrying, waitErr := waitForRetry(ctx, delay)
+		if waitErr != nil {
+			return document{}, &AttemptsError{Errors: attemptErrors, Err: waitErr, BytesRead: fetched.bytesRead}
+		}
+
+		// The retry wouldn't start before the context deadline
+		if !retrying {
+			return document{}, &AttemptsError{Errors: attemptErrors, Err: err, BytesRead: fetched.bytesRead}
+		}
+		metrics.ExtractorFetchRetries.Inc()
 	}
+}
 
-	c.logger.Info().Str("url", url).Msg("Fetching HTML from origin")
-	res, err := c.httpClient.Get(url)
+// fetchOnce makes a single attempt at fetching from the origin, transient failures are returned as retryableError.
+// It fails fast with ErrCircuitOpen while the circuit of the host is open.
+func (c *client) fetchOnce(ctx context.Context, url string, opts Options, cached *cachedResponse, store bool) (_ document, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to get url: %w", err)
+		return document{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	opts.setHeaders(req)
+
+	done, err := c.breaker.allow(req.URL.Host)
+	if err != nil {
+		return document{}, err
+	}
+	defer func() {
+		done(breakerOutcomeOf(ctx, err))
+	}()
+
+	// Revalidate stale documents instead of downloading them again
+	revalidating := cached != nil && cached.hasValidators()
+	if revalidating {
+		c.logger.Info().Str("url", url).Msg("Revalidating cached HTML with origin")
+		cached.setConditionalHeaders(req)
+	} else {
+		c.logger.Info().Str("url", url).Msg("Fetching HTML from origin")
+	}
+
+	// Follow redirects on a copy of the client so the chain of this fetch can be recorded
+	tracker := newRedirectTracker(opts.Redirects, url)
+	httpClient := *c.httpClient
+	httpClient.CheckRedirect = tracker.checkRedirect
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		if tracker.err != nil {
+			return document{}, tracker.abort(tracker.err)
+		}
+
+		err = fmt.Errorf("failed to get url: %w", err)
+		if ctx.Err() != nil || errors.Is(err, urlpolicy.ErrBlocked) {
+			return document{}, err
+		}
+
+		return document{}, &retryableError{err: err}
 	}
 	defer res.Body.Close()
 
+	if opts.Redirects.NoFollow && isRedirect(res.StatusCode) {
+		return document{}, tracker.abort(ErrRedirectNotFollowed)
+	}
+
+	finalURL := finalURLOf(res, url)
+
+	if revalidating && res.StatusCode == http.StatusNotModified {
+		directives := parseCacheControl(res.Header.Get("Cache-Control"))
+		cached.updateFreshness(res.Header, directives, time.Now())
+		cached.FinalURL = finalURL
+		cached.Redirects = tracker.redirects
+		// The document is served this time but not kept fresh once the origin says it mustn't be stored
+		if directives.storable() {
+			c.setCachedResponse(url, cached)
+		}
+
+		return cached.document(url, SourceRevalidated), nil
+	}
+
+	if isRetryableStatus(res.StatusCode) {
+		return document{}, &retryableError{
+			err:        fmt.Errorf("unexpected status code: %s", res.Status),
+			retryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
 	if res.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %s", res.Status)
+		return document{}, fmt.Errorf("unexpected status code: %s", res.Status)
 	}
 
-	// Read all the data from the ReadCloser
-	data, err := io.ReadAll(res.Body)
+	// Only HTML bodies within the size limit are read whole
+	body, bytesRead, err := readHTMLBody(res.Body, res.Header, res.ContentLength, c.maxBodyBytes)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		// What was read is reported even though the page is rejected
+		var contentTypeErr *ContentTypeError
+		if errors.Is(err, ErrBodyTooLarge) || errors.As(err, &contentTypeErr) || ctx.Err() != nil {
+			return document{bytesRead: bytesRead}, err
+		}
+
+		return document{bytesRead: bytesRead}, &retryableError{err: err}
 	}
 
-	// Store result to cache
-	html := string(data)
-	c.resultCache.Set(url, html)
+	// Pages are parsed and cached as UTF-8 whatever they were served in
+	html, charsetName, err := decodeHTML(body, res.Header.Get("Content-Type"))
+	if err != nil {
+		return document{}, err
+	}
+
+	// Store result to cache unless the origin forbids it
+	if entry, ok := newCachedResponse(res.Header, html, time.Now()); ok && store {
+		entry.FinalURL = finalURL
+		entry.Redirects = tracker.redirects
+		entry.Charset = charsetName
+		c.setCachedResponse(url, entry)
+	}
 
-	return html, nil
+	return document{
+		html:      html,
+		source:    SourceOrigin,
+		finalURL:  finalURL,
+		redirects: tracker.redirects,
+		bytesRead: bytesRead,
+		charset:   charsetName,
+	}, nil
 }
 
-func getKeywordCounts(doc *goquery.Document, keywords []string) KeywordCounts {
-	keywordCounts := KeywordCounts{}
+// getCachedResponse returns nil when the url isn't cached or the cached value can't be decoded
+func (c *client) getCachedResponse(url string) *cachedResponse {
+	v, exists := c.resultCache.Get(url)
+	if !exists {
+		return nil
+	}
+
+	entry, err := decodeCachedResponse(v)
+	if err != nil {
+		c.logger.Error().Err(err).Str("url", url).Msg("Failed to decode cached response, ignoring it")
+		return nil
+	}
+
+	return entry
+}
 
-	for _, keyword := range keywords {
-		count := strings.Count(doc.Text(), keyword)
-		keywordCounts[keyword] = count
+func (c *client) setCachedResponse(url string, entry *cachedResponse) {
+	v, err := encodeCachedResponse(entry)
+	if err != nil {
+		c.logger.Error().Err(err).Str("url", url).Msg("Failed to encode cached response")
+		return
 	}
 
-	return keywordCounts
+	c.resultCache.Set(url, v)
 }
@@ -2,13 +2,26 @@ package extractor_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/jponc/domain-crawler/internal/extractor"
+	"github.com/jponc/domain-crawler/internal/metrics"
+	"github.com/jponc/domain-crawler/internal/urlpolicy"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
 )
 
 type roundTripFunc func(r *http.Request) (*http.Response, error)
@@ -42,6 +55,15 @@ func (m *mockCache) Set(k, v string) {
 	}
 }
 
+// cachedHTML encodes html the way the extractor stores documents in its cache
+func cachedHTML(html string, age time.Duration) string {
+	data, _ := json.Marshal(map[string]any{
+		"body":      html,
+		"stored_at": time.Now().Add(-age),
+	})
+	return string(data)
+}
+
 func TestClient_Extract(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -58,7 +80,7 @@ func TestClient_Extract(t *testing.T) {
 			keywords: []string{"keyword1", "keyword2"},
 			mockExtractorCache: &mockCache{
 				getFn: func(k string) (string, bool) {
-					return `
+					return cachedHTML(`
 						<html>
 							<head>
 								<title>Example Domain</title>
@@ -71,18 +93,29 @@ func TestClient_Extract(t *testing.T) {
 								<a href="http://example.com/link1">Link 1</a>
 								<a href="http://example.com/link2">Link 2</a>
 							</body>
-						</html>`, true
+						</html>`, time.Minute), true
 				},
 			},
 			expectedResult: &extractor.ExtractResult{
 				URL:              "http://example.com",
+				FinalURL:         "http://example.com",
 				Title:            "Example Domain",
 				MetaDescriptions: []string{"This is the first meta description.", "This is the second meta description, which might be ignored by search engines."},
-				Links:            []string{"http://example.com/link1", "http://example.com/link2"},
+				Links: []extractor.Link{
+					{URL: "http://example.com/link1", Text: "Link 1", Rel: []string{}, Class: extractor.LinkInternal},
+					{URL: "http://example.com/link2", Text: "Link 2", Rel: []string{}, Class: extractor.LinkInternal},
+				},
 				KeywordCounts: map[string]int{
 					"keyword1": 2,
 					"keyword2": 1,
 				},
+				KeywordResults: map[string]extractor.KeywordResult{
+					"keyword1": {Count: 2, Regions: extractor.KeywordRegions{Body: 2}, Density: 28.57, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring}},
+					"keyword2": {Count: 1, Regions: extractor.KeywordRegions{Body: 1}, Density: 14.29, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring}},
+				},
+				WordCount: 7,
+				Source:    extractor.SourceCache,
+				CacheAge:  time.Minute,
 			},
 		},
 		{
@@ -146,20 +179,30 @@ func TestClient_Extract(t *testing.T) {
 				}, nil
 			},
 			expectedResult: &extractor.ExtractResult{
-				URL:   "http://example.com",
-				Title: "Example Domain",
+				URL:       "http://example.com",
+				FinalURL:  "http://example.com",
+				Attempts:  1,
+				BytesRead: 493,
+				Charset:   "utf-8",
+				Title:     "Example Domain",
 				MetaDescriptions: []string{
 					"This is the first meta description.",
 					"This is the second meta description, which might be ignored by search engines.",
 				},
-				Links: []string{
-					"http://example.com/link1",
-					"http://example.com/link2",
+				Links: []extractor.Link{
+					{URL: "http://example.com/link1", Text: "Link 1", Rel: []string{}, Class: extractor.LinkInternal},
+					{URL: "http://example.com/link2", Text: "Link 2", Rel: []string{}, Class: extractor.LinkInternal},
 				},
 				KeywordCounts: map[string]int{
 					"keyword1": 2,
 					"keyword2": 1,
 				},
+				KeywordResults: map[string]extractor.KeywordResult{
+					"keyword1": {Count: 2, Regions: extractor.KeywordRegions{Body: 2}, Density: 28.57, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring}},
+					"keyword2": {Count: 1, Regions: extractor.KeywordRegions{Body: 1}, Density: 14.29, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring}},
+				},
+				WordCount: 7,
+				Source:    extractor.SourceOrigin,
 			},
 		},
 	}
@@ -172,9 +215,9 @@ func TestClient_Extract(t *testing.T) {
 				Transport: tt.roundTripFunc,
 			}
 
-			client := extractor.NewExtractorClient(httpClient, tt.mockExtractorCache)
+			client := extractor.NewExtractorClient(httpClient, tt.mockExtractorCache, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
 
-			result, err := client.Extract(ctx, tt.url, tt.keywords)
+			result, err := client.Extract(ctx, tt.url, tt.keywords, extractor.Options{})
 			if tt.expectedError != "" {
 				require.Error(t, err)
 				require.EqualError(t, err, tt.expectedError)
@@ -182,7 +225,1659 @@ func TestClient_Extract(t *testing.T) {
 			}
 
 			require.NoError(t, err)
+
+			// The cache age keeps growing while the test runs
+			require.InDelta(t, tt.expectedResult.CacheAge, result.CacheAge, float64(time.Second))
+			result.CacheAge = tt.expectedResult.CacheAge
+
 			require.Equal(t, tt.expectedResult, result)
 		})
 	}
 }
+
+func TestClient_ExtractAbortsFetchWhenContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	httpClient := &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			// Simulate a slow origin which only returns once the request is cancelled
+			cancel()
+			<-r.Context().Done()
+			return nil, r.Context().Err()
+		}),
+	}
+
+	client := extractor.NewExtractorClient(httpClient, &mockCache{}, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+	_, err := client.Extract(ctx, "http://example.com", []string{"keyword1"}, extractor.Options{})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestClient_ExtractHTTPCaching(t *testing.T) {
+	// response builds an origin response with the given status, headers and title
+	response := func(statusCode int, header http.Header, title string) *http.Response {
+		if header == nil {
+			header = http.Header{}
+		}
+
+		return &http.Response{
+			StatusCode: statusCode,
+			Status:     http.StatusText(statusCode),
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader("<html><head><title>" + title + "</title></head></html>")),
+		}
+	}
+
+	tests := []struct {
+		name                    string
+		responses               []*http.Response
+		expectedSources         []extractor.FetchSource
+		expectedTitles          []string
+		expectedRequests        int
+		expectedIfNoneMatch     string
+		expectedIfModifiedSince string
+	}{
+		{
+			name: "serves fresh documents from cache within max-age",
+			responses: []*http.Response{
+				response(http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}}, "v1"),
+			},
+			expectedSources:  []extractor.FetchSource{extractor.SourceOrigin, extractor.SourceCache},
+			expectedTitles:   []string{"v1", "v1"},
+			expectedRequests: 1,
+		},
+		{
+			name: "serves fresh documents from cache before expires",
+			responses: []*http.Response{
+				response(http.StatusOK, http.Header{
+					"Date":    {"Mon, 02 Sep 2024 10:00:00 GMT"},
+					"Expires": {"Mon, 02 Sep 2024 11:00:00 GMT"},
+				}, "v1"),
+			},
+			expectedSources:  []extractor.FetchSource{extractor.SourceOrigin, extractor.SourceCache},
+			expectedTitles:   []string{"v1", "v1"},
+			expectedRequests: 1,
+		},
+		{
+			name: "doesn't store no-store responses",
+			responses: []*http.Response{
+				response(http.StatusOK, http.Header{"Cache-Control": {"no-store"}}, "v1"),
+				response(http.StatusOK, http.Header{"Cache-Control": {"no-store"}}, "v2"),
+			},
+			expectedSources:  []extractor.FetchSource{extractor.SourceOrigin, extractor.SourceOrigin},
+			expectedTitles:   []string{"v1", "v2"},
+			expectedRequests: 2,
+		},
+		{
+			name: "doesn't store private responses",
+			responses: []*http.Response{
+				response(http.StatusOK, http.Header{"Cache-Control": {"private, max-age=60"}}, "v1"),
+				response(http.StatusOK, http.Header{"Cache-Control": {"Private"}}, "v2"),
+			},
+			expectedSources:  []extractor.FetchSource{extractor.SourceOrigin, extractor.SourceOrigin},
+			expectedTitles:   []string{"v1", "v2"},
+			expectedRequests: 2,
+		},
+		{
+			name: "doesn't refresh documents revalidated as private",
+			responses: []*http.Response{
+				response(http.StatusOK, http.Header{"Cache-Control": {"max-age=0"}, "Etag": {`"v1"`}}, "v1"),
+				response(http.StatusNotModified, http.Header{"Cache-Control": {"private, max-age=60"}}, ""),
+				response(http.StatusNotModified, http.Header{"Cache-Control": {"private, max-age=60"}}, ""),
+			},
+			expectedSources:     []extractor.FetchSource{extractor.SourceOrigin, extractor.SourceRevalidated, extractor.SourceRevalidated},
+			expectedTitles:      []string{"v1", "v1", "v1"},
+			expectedRequests:    3,
+			expectedIfNoneMatch: `"v1"`,
+		},
+		{
+			name: "revalidates stale documents with their etag",
+			responses: []*http.Response{
+				response(http.StatusOK, http.Header{"Cache-Control": {"max-age=0"}, "Etag": {`"v1"`}}, "v1"),
+				response(http.StatusNotModified, http.Header{"Cache-Control": {"max-age=60"}}, ""),
+			},
+			expectedSources:     []extractor.FetchSource{extractor.SourceOrigin, extractor.SourceRevalidated, extractor.SourceCache},
+			expectedTitles:      []string{"v1", "v1", "v1"},
+			expectedRequests:    2,
+			expectedIfNoneMatch: `"v1"`,
+		},
+		{
+			name: "revalidates no-cache documents with their last modified date",
+			responses: []*http.Response{
+				response(http.StatusOK, http.Header{"Cache-Control": {"no-cache"}, "Last-Modified": {"Mon, 02 Sep 2024 10:00:00 GMT"}}, "v1"),
+				response(http.StatusNotModified, http.Header{"Cache-Control": {"no-cache"}}, ""),
+			},
+			expectedSources:         []extractor.FetchSource{extractor.SourceOrigin, extractor.SourceRevalidated},
+			expectedTitles:          []string{"v1", "v1"},
+			expectedRequests:        2,
+			expectedIfModifiedSince: "Mon, 02 Sep 2024 10:00:00 GMT",
+		},
+		{
+			name: "downloads the document again when it changed",
+			responses: []*http.Response{
+				response(http.StatusOK, http.Header{"Cache-Control": {"max-age=0"}, "Etag": {`"v1"`}}, "v1"),
+				response(http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}, "Etag": {`"v2"`}}, "v2"),
+			},
+			expectedSources:     []extractor.FetchSource{extractor.SourceOrigin, extractor.SourceOrigin, extractor.SourceCache},
+			expectedTitles:      []string{"v1", "v2", "v2"},
+			expectedRequests:    2,
+			expectedIfNoneMatch: `"v1"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// Use a real map so entries survive between calls
+			store := map[string]string{}
+			mockCache := &mockCache{
+				getFn: func(k string) (string, bool) {
+					v, ok := store[k]
+					return v, ok
+				},
+				setFn: func(k, v string) {
+					store[k] = v
+				},
+			}
+
+			requests := []*http.Request{}
+			httpClient := &http.Client{
+				Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					requests = append(requests, r)
+					return tt.responses[len(requests)-1], nil
+				}),
+			}
+
+			client := extractor.NewExtractorClient(httpClient, mockCache, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+			for i, expectedSource := range tt.expectedSources {
+				result, err := client.Extract(ctx, "http://example.com", nil, extractor.Options{})
+				require.NoError(t, err)
+				require.Equal(t, expectedSource, result.Source)
+				require.Equal(t, tt.expectedTitles[i], result.Title)
+			}
+
+			require.Len(t, requests, tt.expectedRequests)
+			require.Empty(t, requests[0].Header.Get("If-None-Match"))
+			if len(requests) > 1 {
+				require.Equal(t, tt.expectedIfNoneMatch, requests[1].Header.Get("If-None-Match"))
+				require.Equal(t, tt.expectedIfModifiedSince, requests[1].Header.Get("If-Modified-Since"))
+			}
+		})
+	}
+}
+
+func TestClient_ExtractCoalescesConcurrentFetches(t *testing.T) {
+	const callers = 5
+
+	release := make(chan struct{})
+	var requests atomic.Int32
+	httpClient := &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			requests.Add(1)
+			<-release
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("<html><head><title>Shared</title></head></html>")),
+			}, nil
+		}),
+	}
+
+	client := extractor.NewExtractorClient(httpClient, &mockCache{}, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+	coalescedBefore := testutil.ToFloat64(metrics.ExtractorFetchesCoalesced)
+
+	var wg sync.WaitGroup
+	results := make([]*extractor.ExtractResult, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// Equivalent spellings of the URL share the same fetch
+			url := "http://example.com"
+			if i%2 == 1 {
+				url = "HTTP://EXAMPLE.COM:80/"
+			}
+
+			result, err := client.Extract(context.Background(), url, nil, extractor.Options{})
+			require.NoError(t, err)
+			results[i] = result
+		}(i)
+	}
+
+	// Wait for every caller to join the in-flight fetch before letting it finish
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.ExtractorFetchesCoalesced)-coalescedBefore == callers-1
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int32(1), requests.Load())
+	for _, result := range results {
+		require.Equal(t, "Shared", result.Title)
+	}
+}
+
+func TestClient_ExtractKeepsSharedFetchWhileCallersWait(t *testing.T) {
+	release := make(chan struct{})
+	httpClient := &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return nil, r.Context().Err()
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("<html><head><title>Shared</title></head></html>")),
+			}, nil
+		}),
+	}
+
+	client := extractor.NewExtractorClient(httpClient, &mockCache{}, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+	coalescedBefore := testutil.ToFloat64(metrics.ExtractorFetchesCoalesced)
+
+	// Two callers share the fetch, the first one gives up on it
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := client.Extract(firstCtx, "http://example.com", nil, extractor.Options{})
+		firstErr <- err
+	}()
+
+	secondResult := make(chan *extractor.ExtractResult)
+	go func() {
+		result, err := client.Extract(context.Background(), "http://example.com", nil, extractor.Options{})
+		require.NoError(t, err)
+		secondResult <- result
+	}()
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.ExtractorFetchesCoalesced)-coalescedBefore == 1
+	}, time.Second, time.Millisecond)
+
+	cancelFirst()
+	require.ErrorIs(t, <-firstErr, context.Canceled)
+
+	// The second caller still gets the shared result
+	close(release)
+	require.Equal(t, "Shared", (<-secondResult).Title)
+}
+
+func TestClient_ExtractCachePolicy(t *testing.T) {
+	maxAge := func(d time.Duration) *time.Duration {
+		return &d
+	}
+
+	tests := []struct {
+		name             string
+		policy           extractor.CachePolicy
+		expectedSource   extractor.FetchSource
+		expectedTitle    string
+		expectedRequests int
+		expectedStored   bool
+	}{
+		{
+			name:           "uses the cached document by default",
+			policy:         extractor.CachePolicy{},
+			expectedSource: extractor.SourceCache,
+			expectedTitle:  "cached",
+		},
+		{
+			name:           "uses the cached document younger than max age",
+			policy:         extractor.CachePolicy{Mode: extractor.CacheModeUse, MaxAge: maxAge(time.Hour)},
+			expectedSource: extractor.SourceCache,
+			expectedTitle:  "cached",
+		},
+		{
+			name:             "fetches from origin when the cached document is older than max age",
+			policy:           extractor.CachePolicy{Mode: extractor.CacheModeUse, MaxAge: maxAge(5 * time.Minute)},
+			expectedSource:   extractor.SourceOrigin,
+			expectedTitle:    "origin",
+			expectedRequests: 1,
+			expectedStored:   true,
+		},
+		{
+			name:             "refreshes the cached document from origin",
+			policy:           extractor.CachePolicy{Mode: extractor.CacheModeRefresh},
+			expectedSource:   extractor.SourceOrigin,
+			expectedTitle:    "origin",
+			expectedRequests: 1,
+			expectedStored:   true,
+		},
+		{
+			name:             "bypasses the cache without storing the document",
+			policy:           extractor.CachePolicy{Mode: extractor.CacheModeBypass},
+			expectedSource:   extractor.SourceOrigin,
+			expectedTitle:    "origin",
+			expectedRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := false
+			mockCache := &mockCache{
+				getFn: func(k string) (string, bool) {
+					return cachedHTML("<html><head><title>cached</title></head></html>", 10*time.Minute), true
+				},
+				setFn: func(k, v string) {
+					stored = true
+				},
+			}
+
+			requests := 0
+			httpClient := &http.Client{
+				Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					requests++
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{},
+						Body:       io.NopCloser(strings.NewReader("<html><head><title>origin</title></head></html>")),
+					}, nil
+				}),
+			}
+
+			client := extractor.NewExtractorClient(httpClient, mockCache, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+			result, err := client.Extract(context.Background(), "http://example.com", nil, extractor.Options{Cache: tt.policy})
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedSource, result.Source)
+			require.Equal(t, tt.expectedTitle, result.Title)
+			require.Equal(t, tt.expectedRequests, requests)
+			require.Equal(t, tt.expectedStored, stored)
+
+			if tt.expectedSource == extractor.SourceCache {
+				require.InDelta(t, 10*time.Minute, result.CacheAge, float64(time.Second))
+			} else {
+				require.Zero(t, result.CacheAge)
+			}
+		})
+	}
+}
+
+func TestClient_ExtractLinks(t *testing.T) {
+	tests := []struct {
+		name          string
+		url           string
+		body          string
+		opts          extractor.Options
+		expectedLinks []extractor.Link
+	}{
+		{
+			name: "resolves relative links and drops non http links",
+			url:  "https://example.com/blog/post",
+			body: `
+				<a href="other">Other</a>
+				<a href="/about">About</a>
+				<a href="mailto:hello@example.com">Mail</a>
+				<a href="javascript:void(0)">Click</a>
+				<a href="">Empty</a>
+				<a>No href</a>`,
+			expectedLinks: []extractor.Link{
+				{URL: "https://example.com/blog/other", Text: "Other", Rel: []string{}, Class: extractor.LinkInternal},
+				{URL: "https://example.com/about", Text: "About", Rel: []string{}, Class: extractor.LinkInternal},
+			},
+		},
+		{
+			name: "resolves links against the base href",
+			url:  "https://example.com/blog/post",
+			body: `
+				<base href="/docs/">
+				<a href="intro">Intro</a>`,
+			expectedLinks: []extractor.Link{
+				{URL: "https://example.com/docs/intro", Text: "Intro", Rel: []string{}, Class: extractor.LinkInternal},
+			},
+		},
+		{
+			name: "normalizes and deduplicates links",
+			url:  "https://example.com",
+			body: `
+				<a href="HTTPS://Example.com:443/a#top">  First
+					occurrence </a>
+				<a href="https://example.com/a">Second occurrence</a>
+				<a href="https://example.com">Home</a>`,
+			expectedLinks: []extractor.Link{
+				{URL: "https://example.com/a", Text: "First occurrence", Rel: []string{}, Class: extractor.LinkInternal},
+				{URL: "https://example.com/", Text: "Home", Rel: []string{}, Class: extractor.LinkInternal},
+			},
+		},
+		{
+			name: "keeps the brackets of IPv6 hosts",
+			url:  "http://[::1]/",
+			body: `
+				<a href="http://[::1]:80/a">Loopback</a>
+				<a href="https://[2001:db8::1]:443/b">Other</a>`,
+			expectedLinks: []extractor.Link{
+				{URL: "http://[::1]/a", Text: "Loopback", Rel: []string{}, Class: extractor.LinkInternal},
+				{URL: "https://[2001:db8::1]/b", Text: "Other", Rel: []string{}, Class: extractor.LinkExternal},
+			},
+		},
+		{
+			name: "strips tracking params when asked to",
+			url:  "https://example.com",
+			body: `
+				<a href="/a?utm_source=news&id=1">With utm</a>
+				<a href="/a?id=1&gclid=abc">With gclid</a>`,
+			opts: extractor.Options{StripTrackingParams: true},
+			expectedLinks: []extractor.Link{
+				{URL: "https://example.com/a?id=1", Text: "With utm", Rel: []string{}, Class: extractor.LinkInternal},
+			},
+		},
+		{
+			name: "classifies links and keeps qualifying rel values",
+			url:  "https://www.example.com",
+			body: `
+				<a href="/a" rel="nofollow noopener">Internal</a>
+				<a href="https://blog.example.com" rel="UGC nofollow">Subdomain</a>
+				<a href="https://other.com" rel="sponsored">External</a>`,
+			expectedLinks: []extractor.Link{
+				{URL: "https://www.example.com/a", Text: "Internal", Rel: []string{"nofollow"}, Class: extractor.LinkInternal},
+				{URL: "https://blog.example.com/", Text: "Subdomain", Rel: []string{"ugc", "nofollow"}, Class: extractor.LinkSubdomain},
+				{URL: "https://other.com/", Text: "External", Rel: []string{"sponsored"}, Class: extractor.LinkExternal},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := &http.Client{
+				Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{},
+						Body:       io.NopCloser(strings.NewReader("<html><head></head><body>" + tt.body + "</body></html>")),
+					}, nil
+				}),
+			}
+
+			client := extractor.NewExtractorClient(httpClient, &mockCache{}, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+			result, err := client.Extract(context.Background(), tt.url, nil, tt.opts)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedLinks, result.Links)
+		})
+	}
+}
+
+// route is a canned origin response used by redirect tests
+type route struct {
+	status   int
+	location string
+	body     string
+}
+
+func routedHTTPClient(routes map[string]route, calls *int) *http.Client {
+	return &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			if calls != nil {
+				*calls++
+			}
+
+			rt, ok := routes[r.URL.String()]
+			if !ok {
+				rt = route{status: http.StatusNotFound}
+			}
+
+			header := http.Header{}
+			if rt.location != "" {
+				header.Set("Location", rt.location)
+			}
+
+			return &http.Response{
+				StatusCode: rt.status,
+				Status:     http.StatusText(rt.status),
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader(rt.body)),
+				Request:    r,
+			}, nil
+		}),
+	}
+}
+
+func TestClient_ExtractRedirects(t *testing.T) {
+	tests := []struct {
+		name              string
+		routes            map[string]route
+		opts              extractor.Options
+		expectedFinalURL  string
+		expectedRedirects []extractor.Redirect
+		expectedDowngrade bool
+		expectedLinks     []extractor.Link
+		expectedErr       error
+	}{
+		{
+			name: "reports the redirect chain and resolves links against the final URL",
+			routes: map[string]route{
+				"http://example.com/a":  {status: http.StatusMovedPermanently, location: "https://example.com/b"},
+				"https://example.com/b": {status: http.StatusFound, location: "/docs/c"},
+				"https://example.com/docs/c": {
+					status: http.StatusOK,
+					body:   `<html><body><a href="d">D</a></body></html>`,
+				},
+			},
+			expectedFinalURL: "https://example.com/docs/c",
+			expectedRedirects: []extractor.Redirect{
+				{URL: "http://example.com/a", StatusCode: http.StatusMovedPermanently, Location: "https://example.com/b"},
+				{URL: "https://example.com/b", StatusCode: http.StatusFound, Location: "/docs/c"},
+			},
+			expectedLinks: []extractor.Link{
+				{URL: "https://example.com/docs/d", Text: "D", Rel: []string{}, Class: extractor.LinkInternal},
+			},
+		},
+		{
+			name: "detects https to http downgrades",
+			routes: map[string]route{
+				"http://example.com/a":  {status: http.StatusMovedPermanently, location: "https://example.com/b"},
+				"https://example.com/b": {status: http.StatusFound, location: "http://example.com/c"},
+				"http://example.com/c":  {status: http.StatusOK, body: "<html></html>"},
+			},
+			expectedFinalURL: "http://example.com/c",
+			expectedRedirects: []extractor.Redirect{
+				{URL: "http://example.com/a", StatusCode: http.StatusMovedPermanently, Location: "https://example.com/b"},
+				{URL: "https://example.com/b", StatusCode: http.StatusFound, Location: "http://example.com/c", Downgrade: true},
+			},
+			expectedDowngrade: true,
+			expectedLinks:     []extractor.Link{},
+		},
+		{
+			name: "returns error on redirect loops",
+			routes: map[string]route{
+				"http://example.com/a": {status: http.StatusFound, location: "/b"},
+				"http://example.com/b": {status: http.StatusFound, location: "/a"},
+			},
+			expectedRedirects: []extractor.Redirect{
+				{URL: "http://example.com/a", StatusCode: http.StatusFound, Location: "/b"},
+				{URL: "http://example.com/b", StatusCode: http.StatusFound, Location: "/a"},
+			},
+			expectedErr: extractor.ErrRedirectLoop,
+		},
+		{
+			name: "returns error when the chain is longer than max redirects",
+			routes: map[string]route{
+				"http://example.com/a": {status: http.StatusFound, location: "/b"},
+				"http://example.com/b": {status: http.StatusFound, location: "/c"},
+				"http://example.com/c": {status: http.StatusOK, body: "<html></html>"},
+			},
+			opts: extractor.Options{Redirects: extractor.RedirectPolicy{MaxRedirects: 1}},
+			expectedRedirects: []extractor.Redirect{
+				{URL: "http://example.com/a", StatusCode: http.StatusFound, Location: "/b"},
+				{URL: "http://example.com/b", StatusCode: http.StatusFound, Location: "/c"},
+			},
+			expectedErr: extractor.ErrTooManyRedirects,
+		},
+		{
+			name: "returns error without following redirects when asked to",
+			routes: map[string]route{
+				"http://example.com/a": {status: http.StatusMovedPermanently, location: "/b"},
+				"http://example.com/b": {status: http.StatusOK, body: "<html></html>"},
+			},
+			opts: extractor.Options{Redirects: extractor.RedirectPolicy{NoFollow: true}},
+			expectedRedirects: []extractor.Redirect{
+				{URL: "http://example.com/a", StatusCode: http.StatusMovedPermanently, Location: "/b"},
+			},
+			expectedErr: extractor.ErrRedirectNotFollowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := extractor.NewExtractorClient(routedHTTPClient(tt.routes, nil), &mockCache{}, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+			result, err := client.Extract(context.Background(), "http://example.com/a", nil, tt.opts)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+
+				var redirectErr *extractor.RedirectError
+				require.ErrorAs(t, err, &redirectErr)
+				require.Equal(t, tt.expectedRedirects, redirectErr.Redirects)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "http://example.com/a", result.URL)
+			require.Equal(t, tt.expectedFinalURL, result.FinalURL)
+			require.Equal(t, tt.expectedRedirects, result.Redirects)
+			require.Equal(t, tt.expectedDowngrade, result.HTTPSDowngrade)
+			require.Equal(t, tt.expectedLinks, result.Links)
+		})
+	}
+}
+
+func TestClient_ExtractCachesRedirectChain(t *testing.T) {
+	routes := map[string]route{
+		"http://example.com/a": {status: http.StatusMovedPermanently, location: "/b"},
+		"http://example.com/b": {status: http.StatusOK, body: "<html></html>"},
+	}
+	calls := 0
+	stored := map[string]string{}
+	resultCache := &mockCache{
+		getFn: func(k string) (string, bool) {
+			v, ok := stored[k]
+			return v, ok
+		},
+		setFn: func(k, v string) {
+			stored[k] = v
+		},
+	}
+	client := extractor.NewExtractorClient(routedHTTPClient(routes, &calls), resultCache, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+	fetched, err := client.Extract(context.Background(), "http://example.com/a", nil, extractor.Options{})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+
+	// The cached document keeps the chain it was reached through
+	cached, err := client.Extract(context.Background(), "http://example.com/a", nil, extractor.Options{})
+	require.NoError(t, err)
+	require.Equal(t, extractor.SourceCache, cached.Source)
+	require.Equal(t, fetched.FinalURL, cached.FinalURL)
+	require.Equal(t, fetched.Redirects, cached.Redirects)
+	require.Equal(t, 2, calls)
+
+	// The cached document was reached through a redirect, which isn't allowed anymore
+	_, err = client.Extract(context.Background(), "http://example.com/a", nil, extractor.Options{
+		Redirects: extractor.RedirectPolicy{NoFollow: true},
+	})
+	require.ErrorIs(t, err, extractor.ErrRedirectNotFollowed)
+	require.Equal(t, 3, calls)
+}
+
+// attempt is the canned outcome of a single fetch used by retry tests
+type attempt struct {
+	status     int
+	retryAfter string
+	err        error
+}
+
+func sequencedHTTPClient(attempts []attempt, calls *int) *http.Client {
+	return &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			a := attempts[min(*calls, len(attempts)-1)]
+			*calls++
+			if a.err != nil {
+				return nil, a.err
+			}
+
+			header := http.Header{}
+			if a.retryAfter != "" {
+				header.Set("Retry-After", a.retryAfter)
+			}
+
+			return &http.Response{
+				StatusCode: a.status,
+				Status:     fmt.Sprintf("%d %s", a.status, http.StatusText(a.status)),
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader("<html><title>Retried</title></html>")),
+				Request:    r,
+			}, nil
+		}),
+	}
+}
+
+func TestClient_ExtractRetries(t *testing.T) {
+	retryPolicy := extractor.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+	tests := []struct {
+		name                  string
+		attempts              []attempt
+		retryPolicy           extractor.RetryPolicy
+		timeout               time.Duration
+		expectedCalls         int
+		expectedAttemptErrors []string
+		expectedErr           string
+		expectedMinDuration   time.Duration
+	}{
+		{
+			name: "retries 5xx responses and network errors until one succeeds",
+			attempts: []attempt{
+				{status: http.StatusServiceUnavailable},
+				{err: errors.New("connection reset")},
+				{status: http.StatusOK},
+			},
+			retryPolicy:   retryPolicy,
+			expectedCalls: 3,
+			expectedAttemptErrors: []string{
+				"unexpected status code: 503 Service Unavailable",
+				"failed to get url: Get \"http://example.com\": connection reset",
+			},
+		},
+		{
+			name:                  "doesn't retry other error statuses",
+			attempts:              []attempt{{status: http.StatusNotFound}},
+			retryPolicy:           retryPolicy,
+			expectedCalls:         1,
+			expectedAttemptErrors: []string{"unexpected status code: 404 Not Found"},
+			expectedErr:           "failed to fetch html: unexpected status code: 404 Not Found",
+		},
+		{
+			name:          "gives up after the last attempt",
+			attempts:      []attempt{{status: http.StatusBadGateway}},
+			retryPolicy:   retryPolicy,
+			expectedCalls: 3,
+			expectedAttemptErrors: []string{
+				"unexpected status code: 502 Bad Gateway",
+				"unexpected status code: 502 Bad Gateway",
+				"unexpected status code: 502 Bad Gateway",
+			},
+			expectedErr: "failed to fetch html: unexpected status code: 502 Bad Gateway (after 3 attempts)",
+		},
+		{
+			name: "waits for the delay asked by the Retry-After header",
+			attempts: []attempt{
+				{status: http.StatusTooManyRequests, retryAfter: "1"},
+				{status: http.StatusOK},
+			},
+			retryPolicy:           extractor.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Second},
+			expectedCalls:         2,
+			expectedAttemptErrors: []string{"unexpected status code: 429 Too Many Requests"},
+			expectedMinDuration:   time.Second,
+		},
+		{
+			name:                  "doesn't retry when Retry-After is longer than the max delay",
+			attempts:              []attempt{{status: http.StatusTooManyRequests, retryAfter: "3600"}},
+			retryPolicy:           retryPolicy,
+			expectedCalls:         1,
+			expectedAttemptErrors: []string{"unexpected status code: 429 Too Many Requests"},
+			expectedErr:           "failed to fetch html: unexpected status code: 429 Too Many Requests",
+		},
+		{
+			name: "doesn't retry when Retry-After overflows a duration",
+			// Multiplied into nanoseconds it would wrap around to 290ms, within the max delay
+			attempts:              []attempt{{status: http.StatusTooManyRequests, retryAfter: "18446744074"}},
+			retryPolicy:           extractor.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Second},
+			expectedCalls:         1,
+			expectedAttemptErrors: []string{"unexpected status code: 429 Too Many Requests"},
+			expectedErr:           "failed to fetch html: unexpected status code: 429 Too Many Requests",
+		},
+		{
+			name:                  "doesn't retry when Retry-After overflows an integer",
+			attempts:              []attempt{{status: http.StatusTooManyRequests, retryAfter: "99999999999999999999"}},
+			retryPolicy:           retryPolicy,
+			expectedCalls:         1,
+			expectedAttemptErrors: []string{"unexpected status code: 429 Too Many Requests"},
+			expectedErr:           "failed to fetch html: unexpected status code: 429 Too Many Requests",
+		},
+		{
+			name:                  "doesn't retry past the context deadline",
+			attempts:              []attempt{{status: http.StatusInternalServerError}},
+			retryPolicy:           extractor.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour},
+			timeout:               time.Second,
+			expectedCalls:         1,
+			expectedAttemptErrors: []string{"unexpected status code: 500 Internal Server Error"},
+			expectedErr:           "failed to fetch html: unexpected status code: 500 Internal Server Error",
+		},
+		{
+			name:                  "doesn't retry urls blocked by the url policy",
+			attempts:              []attempt{{err: fmt.Errorf("%w: address 10.0.0.1 is internal", urlpolicy.ErrBlocked)}},
+			retryPolicy:           retryPolicy,
+			expectedCalls:         1,
+			expectedAttemptErrors: []string{"failed to get url: Get \"http://example.com\": url blocked: address 10.0.0.1 is internal"},
+			expectedErr:           "failed to fetch html: failed to get url: Get \"http://example.com\": url blocked: address 10.0.0.1 is internal",
+		},
+		{
+			name:                  "doesn't retry without a retry policy",
+			attempts:              []attempt{{status: http.StatusInternalServerError}},
+			expectedCalls:         1,
+			expectedAttemptErrors: []string{"unexpected status code: 500 Internal Server Error"},
+			expectedErr:           "failed to fetch html: unexpected status code: 500 Internal Server Error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := extractor.NewExtractorClient(sequencedHTTPClient(tt.attempts, &calls), &mockCache{}, tt.retryPolicy, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			result, err := client.Extract(ctx, "http://example.com", nil, extractor.Options{})
+			require.GreaterOrEqual(t, time.Since(start), tt.expectedMinDuration)
+			require.Equal(t, tt.expectedCalls, calls)
+
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+
+				var attemptsErr *extractor.AttemptsError
+				require.ErrorAs(t, err, &attemptsErr)
+				require.Equal(t, tt.expectedAttemptErrors, attemptsErr.Errors)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "Retried", result.Title)
+			require.Equal(t, tt.expectedCalls, result.Attempts)
+			require.Equal(t, tt.expectedAttemptErrors, result.AttemptErrors)
+		})
+	}
+}
+
+func TestClient_ExtractCircuitBreaker(t *testing.T) {
+	attempts := []attempt{
+		{status: http.StatusServiceUnavailable},
+		{err: errors.New("connection refused")},
+		{status: http.StatusNotFound},
+		{status: http.StatusServiceUnavailable},
+		{status: http.StatusOK},
+	}
+	calls := 0
+	breaker := extractor.NewCircuitBreaker(extractor.BreakerPolicy{FailureThreshold: 2, OpenDuration: 50 * time.Millisecond})
+	client := extractor.NewExtractorClient(sequencedHTTPClient(attempts, &calls), &mockCache{}, extractor.RetryPolicy{}, breaker, 0)
+
+	extract := func(url string) error {
+		_, err := client.Extract(context.Background(), url, nil, extractor.Options{})
+		return err
+	}
+
+	// Consecutive failures open the circuit
+	require.Error(t, extract("http://example.com/a"))
+	require.Equal(t, []extractor.HostBreaker{{Host: "example.com", State: extractor.BreakerClosed, ConsecutiveFailures: 1}}, breaker.Hosts())
+	require.Error(t, extract("http://example.com/b"))
+	require.Equal(t, 2, calls)
+
+	// Requests fail fast without reaching the host while it is open
+	err := extract("http://example.com/c")
+	require.ErrorIs(t, err, extractor.ErrCircuitOpen)
+	require.EqualError(t, err, "failed to fetch html: circuit open for host example.com")
+	require.Equal(t, 2, calls)
+
+	hosts := breaker.Hosts()
+	require.Len(t, hosts, 1)
+	require.Equal(t, extractor.BreakerOpen, hosts[0].State)
+	require.Equal(t, 2, hosts[0].ConsecutiveFailures)
+	require.Equal(t, hosts[0].OpenedAt.Add(50*time.Millisecond), hosts[0].RetryAt)
+
+	// Other hosts aren't affected, answering with an error status isn't a failure of the host
+	require.Error(t, extract("http://example.org"))
+	require.Equal(t, 3, calls)
+	require.Len(t, breaker.Hosts(), 1)
+
+	// A failed probe opens the circuit again
+	time.Sleep(60 * time.Millisecond)
+	require.Error(t, extract("http://example.com/d"))
+	require.Equal(t, 4, calls)
+	require.ErrorIs(t, extract("http://example.com/e"), extractor.ErrCircuitOpen)
+	require.Equal(t, 4, calls)
+
+	// A successful probe closes it
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, extract("http://example.com/f"))
+	require.Equal(t, 5, calls)
+	require.Empty(t, breaker.Hosts())
+	require.NoError(t, extract("http://example.com/g"))
+	require.Equal(t, 6, calls)
+}
+
+func TestClient_ExtractHeaderOverrides(t *testing.T) {
+	var received []http.Header
+	httpClient := &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			received = append(received, r.Header)
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Cache-Control": {"max-age=3600"}},
+				Body:       io.NopCloser(strings.NewReader("<html><title>" + r.Header.Get("Accept-Language") + "</title></html>")),
+				Request:    r,
+			}, nil
+		}),
+	}
+	stored := map[string]string{}
+	resultCache := &mockCache{
+		getFn: func(k string) (string, bool) {
+			v, ok := stored[k]
+			return v, ok
+		},
+		setFn: func(k, v string) {
+			stored[k] = v
+		},
+	}
+	client := extractor.NewExtractorClient(httpClient, resultCache, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+	// Pages fetched without overrides are cached
+	result, err := client.Extract(context.Background(), "http://example.com", nil, extractor.Options{})
+	require.NoError(t, err)
+	require.Equal(t, extractor.SourceOrigin, result.Source)
+	require.Len(t, stored, 1)
+
+	// Overrides are sent to the origin, the cached page isn't used nor replaced
+	opts := extractor.Options{UserAgent: "custom/1.0", Headers: map[string]string{"accept-language": "fr"}}
+	result, err = client.Extract(context.Background(), "http://example.com", nil, opts)
+	require.NoError(t, err)
+	require.Equal(t, extractor.SourceOrigin, result.Source)
+	require.Equal(t, "fr", result.Title)
+	require.Len(t, received, 2)
+	require.Equal(t, "custom/1.0", received[1].Get("User-Agent"))
+	require.Equal(t, "fr", received[1].Get("Accept-Language"))
+
+	result, err = client.Extract(context.Background(), "http://example.com", nil, extractor.Options{})
+	require.NoError(t, err)
+	require.Equal(t, extractor.SourceCache, result.Source)
+	require.Equal(t, "", result.Title)
+	require.Len(t, received, 2)
+}
+
+func TestClient_ExtractBodyGating(t *testing.T) {
+	page := "<html><title>Page</title></html>"
+
+	tests := []struct {
+		name              string
+		contentType       string
+		body              string
+		contentLength     int64
+		maxBodyBytes      int64
+		expectedBytesRead int64
+		expectedErr       error
+		expectedErrMsg    string
+	}{
+		{
+			name:              "reads html within the limit",
+			contentType:       "text/html; charset=utf-8",
+			body:              page,
+			maxBodyBytes:      int64(len(page)),
+			expectedBytesRead: int64(len(page)),
+		},
+		{
+			name:              "reads xhtml",
+			contentType:       "application/xhtml+xml",
+			body:              page,
+			expectedBytesRead: int64(len(page)),
+		},
+		{
+			name:              "sniffs responses without a content type",
+			body:              page,
+			expectedBytesRead: int64(len(page)),
+		},
+		{
+			name:              "rejects bodies larger than the limit",
+			contentType:       "text/html",
+			body:              page,
+			maxBodyBytes:      10,
+			expectedBytesRead: 11,
+			expectedErr:       extractor.ErrBodyTooLarge,
+			expectedErrMsg:    "failed to fetch html: response body too large: over the limit of 10 bytes",
+		},
+		{
+			name:           "rejects bodies declared larger than the limit without reading them",
+			contentType:    "text/html",
+			body:           page,
+			contentLength:  1 << 30,
+			maxBodyBytes:   10,
+			expectedErr:    extractor.ErrBodyTooLarge,
+			expectedErrMsg: "failed to fetch html: response body too large: 1073741824 bytes over the limit of 10 bytes",
+		},
+		{
+			name:              "rejects other content types",
+			contentType:       "application/json",
+			body:              `{"title": "Page"}`,
+			expectedBytesRead: 17,
+			expectedErr:       extractor.ErrUnsupportedContentType,
+			expectedErrMsg:    "failed to fetch html: unsupported content type: application/json",
+		},
+		{
+			name:              "rejects binary bodies served as html",
+			contentType:       "text/html",
+			body:              "%PDF-1.7\n",
+			expectedBytesRead: 9,
+			expectedErr:       extractor.ErrUnsupportedContentType,
+			expectedErrMsg:    "failed to fetch html: unsupported content type: application/pdf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			httpClient := &http.Client{
+				Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					calls++
+
+					header := http.Header{}
+					if tt.contentType != "" {
+						header.Set("Content-Type", tt.contentType)
+					}
+
+					contentLength := tt.contentLength
+					if contentLength == 0 {
+						contentLength = -1
+					}
+
+					return &http.Response{
+						StatusCode:    http.StatusOK,
+						Header:        header,
+						ContentLength: contentLength,
+						Body:          io.NopCloser(strings.NewReader(tt.body)),
+						Request:       r,
+					}, nil
+				}),
+			}
+			retryPolicy := extractor.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+			client := extractor.NewExtractorClient(httpClient, &mockCache{}, retryPolicy, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), tt.maxBodyBytes)
+
+			result, err := client.Extract(context.Background(), "http://example.com", nil, extractor.Options{})
+
+			// Neither error is worth retrying
+			require.Equal(t, 1, calls)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				require.EqualError(t, err, tt.expectedErrMsg)
+
+				// What was read before the page was rejected is reported all the same
+				var attemptsErr *extractor.AttemptsError
+				require.ErrorAs(t, err, &attemptsErr)
+				require.Equal(t, tt.expectedBytesRead, attemptsErr.BytesRead)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "Page", result.Title)
+			require.Equal(t, tt.expectedBytesRead, result.BytesRead)
+		})
+	}
+}
+
+func TestClient_ExtractCharsets(t *testing.T) {
+	encode := func(enc encoding.Encoding, s string) string {
+		encoded, err := enc.NewEncoder().String(s)
+		require.NoError(t, err)
+		return encoded
+	}
+
+	tests := []struct {
+		name            string
+		contentType     string
+		body            string
+		keywords        []string
+		expectedCharset string
+		expectedTitle   string
+		expectedCounts  extractor.KeywordCounts
+	}{
+		{
+			name:            "decodes the charset of the Content-Type header",
+			contentType:     "text/html; charset=Shift_JIS",
+			body:            encode(japanese.ShiftJIS, "<html><title>日本語のページ</title><body>日本語 日本語</body></html>"),
+			keywords:        []string{"日本語"},
+			expectedCharset: "shift_jis",
+			expectedTitle:   "日本語のページ",
+			expectedCounts:  extractor.KeywordCounts{"日本語": 2},
+		},
+		{
+			name:            "decodes the charset of the meta tag",
+			contentType:     "text/html",
+			body:            encode(charmap.Windows1252, `<html><head><meta charset="windows-1252"><title>Café crème</title></head><body>café</body></html>`),
+			keywords:        []string{"café"},
+			expectedCharset: "windows-1252",
+			expectedTitle:   "Café crème",
+			expectedCounts:  extractor.KeywordCounts{"café": 1},
+		},
+		{
+			name:            "decodes the charset of the http-equiv meta tag",
+			contentType:     "text/html",
+			body:            encode(simplifiedchinese.GBK, `<html><head><meta http-equiv="Content-Type" content="text/html; charset=gbk"><title>中文网页</title></head></html>`),
+			expectedCharset: "gbk",
+			expectedTitle:   "中文网页",
+		},
+		{
+			name:            "prefers the byte order mark",
+			contentType:     "text/html; charset=iso-8859-1",
+			body:            "\xef\xbb\xbf<html><title>Crème brûlée</title></html>",
+			expectedCharset: "utf-8",
+			expectedTitle:   "Crème brûlée",
+		},
+		{
+			name:            "keeps undeclared UTF-8 pages as is",
+			contentType:     "text/html",
+			body:            "<html><title>Page</title><body>" + strings.Repeat(" ", 2048) + "naïve</body></html>",
+			keywords:        []string{"naïve"},
+			expectedCharset: "utf-8",
+			expectedTitle:   "Page",
+			expectedCounts:  extractor.KeywordCounts{"naïve": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := &http.Client{
+				Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{"Content-Type": {tt.contentType}, "Cache-Control": {"max-age=3600"}},
+						Body:       io.NopCloser(strings.NewReader(tt.body)),
+						Request:    r,
+					}, nil
+				}),
+			}
+			stored := map[string]string{}
+			resultCache := &mockCache{
+				getFn: func(k string) (string, bool) {
+					v, ok := stored[k]
+					return v, ok
+				},
+				setFn: func(k, v string) {
+					stored[k] = v
+				},
+			}
+			client := extractor.NewExtractorClient(httpClient, resultCache, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+			expectedCounts := tt.expectedCounts
+			if expectedCounts == nil {
+				expectedCounts = extractor.KeywordCounts{}
+			}
+
+			// Cached pages are stored transcoded along with their charset
+			for _, source := range []extractor.FetchSource{extractor.SourceOrigin, extractor.SourceCache} {
+				result, err := client.Extract(context.Background(), "http://example.com", tt.keywords, extractor.Options{})
+				require.NoError(t, err)
+				require.Equal(t, source, result.Source)
+				require.Equal(t, tt.expectedCharset, result.Charset)
+				require.Equal(t, tt.expectedTitle, result.Title)
+				require.Equal(t, expectedCounts, result.KeywordCounts)
+			}
+		})
+	}
+}
+
+func TestClient_ExtractKeywordOptions(t *testing.T) {
+	body := `<html><body>
+		<p>The Cat chased the cat into the concatenated catalog of cats.</p>
+		<p>Café, café, CAFÉ, cafe and cafe` + "́" + ` in São Paulo_2.</p>
+		<p>Orders #123, #4567 and #89.</p>
+	</body></html>`
+
+	tests := []struct {
+		name           string
+		keyword        string
+		keywordOptions *extractor.KeywordOptions
+		expectedResult extractor.KeywordResult
+	}{
+		{
+			name:           "matches case-sensitive substrings by default",
+			keyword:        "cat",
+			expectedResult: extractor.KeywordResult{Count: 4, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring}},
+		},
+		{
+			name:           "matches substrings regardless of case",
+			keyword:        "cat",
+			keywordOptions: &extractor.KeywordOptions{CaseInsensitive: true},
+			expectedResult: extractor.KeywordResult{Count: 5, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring, CaseInsensitive: true}},
+		},
+		{
+			name:           "matches whole words",
+			keyword:        "cat",
+			keywordOptions: &extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord},
+			expectedResult: extractor.KeywordResult{Count: 1, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord}},
+		},
+		{
+			name:           "matches whole words regardless of case",
+			keyword:        "CAT",
+			keywordOptions: &extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord, CaseInsensitive: true},
+			expectedResult: extractor.KeywordResult{Count: 2, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord, CaseInsensitive: true}},
+		},
+		{
+			name:           "treats connectors as part of words",
+			keyword:        "Paulo",
+			keywordOptions: &extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord},
+			expectedResult: extractor.KeywordResult{Count: 0, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord}},
+		},
+		{
+			name:           "matches composed and decomposed accents",
+			keyword:        "café",
+			expectedResult: extractor.KeywordResult{Count: 2, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring}},
+		},
+		{
+			name:           "folds the case of accented letters",
+			keyword:        "café",
+			keywordOptions: &extractor.KeywordOptions{CaseInsensitive: true},
+			expectedResult: extractor.KeywordResult{Count: 4, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring, CaseInsensitive: true}},
+		},
+		{
+			name:           "folds diacritics",
+			keyword:        "cafe",
+			keywordOptions: &extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord, CaseInsensitive: true, FoldDiacritics: true},
+			expectedResult: extractor.KeywordResult{Count: 5, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord, CaseInsensitive: true, FoldDiacritics: true}},
+		},
+		{
+			name:           "folds diacritics of the keyword",
+			keyword:        "sao",
+			keywordOptions: &extractor.KeywordOptions{CaseInsensitive: true, FoldDiacritics: true},
+			expectedResult: extractor.KeywordResult{Count: 1, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring, CaseInsensitive: true, FoldDiacritics: true}},
+		},
+		{
+			name:           "counts matches of regular expressions",
+			keyword:        `#\d{3,}`,
+			keywordOptions: &extractor.KeywordOptions{Mode: extractor.KeywordModeRegex},
+			expectedResult: extractor.KeywordResult{Count: 2, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeRegex}},
+		},
+		{
+			name:           "counts matches of regular expressions regardless of case",
+			keyword:        `\bcat\w*`,
+			keywordOptions: &extractor.KeywordOptions{Mode: extractor.KeywordModeRegex, CaseInsensitive: true},
+			expectedResult: extractor.KeywordResult{Count: 4, Options: extractor.KeywordOptions{Mode: extractor.KeywordModeRegex, CaseInsensitive: true}},
+		},
+		{
+			name:           "reports invalid regular expressions",
+			keyword:        `(cat`,
+			keywordOptions: &extractor.KeywordOptions{Mode: extractor.KeywordModeRegex},
+			expectedResult: extractor.KeywordResult{
+				Options: extractor.KeywordOptions{Mode: extractor.KeywordModeRegex},
+				Error:   "invalid keyword: error parsing regexp: missing closing ): `(cat`",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := &http.Client{
+				Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+						Body:       io.NopCloser(strings.NewReader(body)),
+						Request:    r,
+					}, nil
+				}),
+			}
+			client := extractor.NewExtractorClient(httpClient, &mockCache{}, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+			opts := extractor.Options{}
+			if tt.keywordOptions != nil {
+				opts.KeywordOptions = map[string]extractor.KeywordOptions{tt.keyword: *tt.keywordOptions}
+			}
+
+			result, err := client.Extract(context.Background(), "http://example.com", []string{tt.keyword}, opts)
+			require.NoError(t, err)
+
+			// Regions are covered by TestClient_ExtractKeywordRegions
+			keywordResult := result.KeywordResults[tt.keyword]
+			require.Equal(t, keywordResult.Count, keywordResult.Regions.Body)
+			keywordResult.Regions = extractor.KeywordRegions{}
+			keywordResult.Density = 0
+			require.Equal(t, tt.expectedResult, keywordResult)
+			require.Equal(t, extractor.KeywordCounts{tt.keyword: tt.expectedResult.Count}, result.KeywordCounts)
+		})
+	}
+}
+
+func TestValidateKeyword(t *testing.T) {
+	tests := []struct {
+		name           string
+		keyword        string
+		keywordOptions extractor.KeywordOptions
+		expectedError  string
+	}{
+		{
+			name:    "accepts substrings",
+			keyword: "cat",
+		},
+		{
+			name:           "accepts regular expressions",
+			keyword:        `(?:[a-z]+\s){100}`,
+			keywordOptions: extractor.KeywordOptions{Mode: extractor.KeywordModeRegex},
+		},
+		{
+			name:           "rejects unknown modes",
+			keyword:        "cat",
+			keywordOptions: extractor.KeywordOptions{Mode: "fuzzy"},
+			expectedError:  `invalid keyword: unknown mode "fuzzy"`,
+		},
+		{
+			name:           "rejects invalid regular expressions",
+			keyword:        `[cat`,
+			keywordOptions: extractor.KeywordOptions{Mode: extractor.KeywordModeRegex},
+			expectedError:  "invalid keyword: error parsing regexp: missing closing ]: `[cat`",
+		},
+		{
+			name:           "rejects long regular expressions",
+			keyword:        strings.Repeat("a", 513),
+			keywordOptions: extractor.KeywordOptions{Mode: extractor.KeywordModeRegex},
+			expectedError:  "invalid keyword: regular expression longer than 512 bytes",
+		},
+		{
+			name:           "rejects complex regular expressions",
+			keyword:        `(\w+\s*){1000}`,
+			keywordOptions: extractor.KeywordOptions{Mode: extractor.KeywordModeRegex},
+			expectedError:  "invalid keyword: regular expression too complex",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := extractor.ValidateKeyword(tt.keyword, tt.keywordOptions)
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				require.ErrorIs(t, err, extractor.ErrInvalidKeyword)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestClient_ExtractKeywordRegions(t *testing.T) {
+	body := `<html>
+		<head>
+			<title>Cat food | Pets</title>
+			<meta name="description" content="The best cat food for your cat." />
+			<style>.cat { color: red; }</style>
+			<script>var cat = "cat";</script>
+		</head>
+		<body>
+			<h1>Cat food</h1>
+			<h2>Why cats love it</h2>
+			<p>Our cat food is made with fish.<br>Every cat agrees.</p>
+			<script>trackCat("cat");</script>
+			<noscript>Enable JavaScript to see the cat</noscript>
+			<img src="/cat.png" alt="A cat eating">
+			<a href="/cat-toys">Cat toys</a>
+			<h6>About us</h6>
+		</body>
+	</html>`
+
+	tests := []struct {
+		name           string
+		keyword        string
+		keywordOptions extractor.KeywordOptions
+		expectedResult extractor.KeywordResult
+	}{
+		{
+			name:           "counts case-sensitive occurrences by region",
+			keyword:        "cat",
+			keywordOptions: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring},
+			expectedResult: extractor.KeywordResult{
+				Count: 3,
+				Regions: extractor.KeywordRegions{
+					MetaDescription: 2,
+					H2:              1,
+					Body:            3,
+					ImageAlt:        1,
+					URLPath:         1,
+				},
+				Density: 15,
+				Options: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring},
+			},
+		},
+		{
+			name:           "counts whole words regardless of case by region",
+			keyword:        "cat food",
+			keywordOptions: extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord, CaseInsensitive: true},
+			expectedResult: extractor.KeywordResult{
+				Count: 2,
+				Regions: extractor.KeywordRegions{
+					Title:           1,
+					MetaDescription: 1,
+					H1:              1,
+					Body:            2,
+					URLPath:         1,
+				},
+				Density: 10,
+				Options: extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord, CaseInsensitive: true},
+			},
+		},
+		{
+			name:           "separates block elements",
+			keyword:        `fish\.\s+every`,
+			keywordOptions: extractor.KeywordOptions{Mode: extractor.KeywordModeRegex, CaseInsensitive: true},
+			expectedResult: extractor.KeywordResult{
+				Count:   1,
+				Regions: extractor.KeywordRegions{Body: 1},
+				Density: 5,
+				Options: extractor.KeywordOptions{Mode: extractor.KeywordModeRegex, CaseInsensitive: true},
+			},
+		},
+		{
+			name:           "counts anchor text",
+			keyword:        "toys",
+			keywordOptions: extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord},
+			expectedResult: extractor.KeywordResult{
+				Count:   1,
+				Regions: extractor.KeywordRegions{Body: 1, AnchorText: 1},
+				Density: 5,
+				Options: extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord},
+			},
+		},
+		{
+			name:           "counts headings by level",
+			keyword:        "about",
+			keywordOptions: extractor.KeywordOptions{CaseInsensitive: true},
+			expectedResult: extractor.KeywordResult{
+				Count:   1,
+				Regions: extractor.KeywordRegions{H6: 1, Body: 1},
+				Density: 5,
+				Options: extractor.KeywordOptions{Mode: extractor.KeywordModeSubstring, CaseInsensitive: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := &http.Client{
+				Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+						Body:       io.NopCloser(strings.NewReader(body)),
+						Request:    r,
+					}, nil
+				}),
+			}
+			client := extractor.NewExtractorClient(httpClient, &mockCache{}, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+			result, err := client.Extract(context.Background(), "http://example.com/pets/cat-food", []string{tt.keyword}, extractor.Options{
+				KeywordOptions: map[string]extractor.KeywordOptions{tt.keyword: tt.keywordOptions},
+			})
+			require.NoError(t, err)
+			require.Equal(t, 20, result.WordCount)
+			require.Equal(t, map[string]extractor.KeywordResult{tt.keyword: tt.expectedResult}, result.KeywordResults)
+			require.Equal(t, extractor.KeywordCounts{tt.keyword: tt.expectedResult.Count}, result.KeywordCounts)
+		})
+	}
+}
+
+func TestClient_ExtractKeywordMatches(t *testing.T) {
+	body := `<html><body>` +
+		`<div id="content"><p>Our cat food is made with fish.</p><p>Every <b>cat</b> agrees, crème brûlée for cats.</p></div>` +
+		`<ul><li>Cat toys</li><li>Toys for the CAT</li></ul>` +
+		`<script>var cat = "cat";</script>` +
+		`</body></html>`
+
+	tests := []struct {
+		name              string
+		keyword           string
+		keywordOptions    extractor.KeywordOptions
+		maxKeywordMatches int
+		expectedCount     int
+		expectedMatches   []extractor.KeywordMatch
+	}{
+		{
+			name:           "returns no matches unless asked for",
+			keyword:        "cat",
+			keywordOptions: extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord},
+			expectedCount:  2,
+		},
+		{
+			name:              "returns the first matches with their snippet, offset and path",
+			keyword:           "cat",
+			keywordOptions:    extractor.KeywordOptions{Mode: extractor.KeywordModeWholeWord, CaseInsensitive: true},
+			maxKeywordMatches: 3,
+			expectedCount:     4,
+			expectedMatches: []extractor.KeywordMatch{
+				{Offset: 4, Length: 3, Snippet: "Our cat food is made with fish. Every cat agree", Path: "div#content > p:nth-of-type(1)"},
+				{Offset: 38, Length: 3, Snippet: "Our cat food is made with fish. Every cat agrees, crème brûlée for cats. Cat toys", Path: "div#content > p:nth-of-type(2) > b"},
+				{Offset: 73, Length: 3, Snippet: "very cat agrees, crème brûlée for cats. Cat toys Toys for the CAT", Path: "html > body > ul > li:nth-of-type(1)"},
+			},
+		},
+		{
+			name:              "maps offsets of folded text back to the visible text",
+			keyword:           "creme brulee for cats",
+			keywordOptions:    extractor.KeywordOptions{FoldDiacritics: true},
+			maxKeywordMatches: 10,
+			expectedCount:     1,
+			expectedMatches: []extractor.KeywordMatch{
+				{Offset: 50, Length: 21, Snippet: "od is made with fish. Every cat agrees, crème brûlée for cats. Cat toys Toys for the CAT", Path: "div#content > p:nth-of-type(2)"},
+			},
+		},
+		{
+			name:              "returns the element holding matches spanning several elements",
+			keyword:           `every\s+cat`,
+			keywordOptions:    extractor.KeywordOptions{Mode: extractor.KeywordModeRegex, CaseInsensitive: true},
+			maxKeywordMatches: 10,
+			expectedCount:     1,
+			expectedMatches: []extractor.KeywordMatch{
+				{Offset: 32, Length: 9, Snippet: "Our cat food is made with fish. Every cat agrees, crème brûlée for cats. Cat toys", Path: "div#content > p:nth-of-type(2)"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := &http.Client{
+				Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+						Body:       io.NopCloser(strings.NewReader(body)),
+						Request:    r,
+					}, nil
+				}),
+			}
+			client := extractor.NewExtractorClient(httpClient, &mockCache{}, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+			result, err := client.Extract(context.Background(), "http://example.com", []string{tt.keyword}, extractor.Options{
+				KeywordOptions:    map[string]extractor.KeywordOptions{tt.keyword: tt.keywordOptions},
+				MaxKeywordMatches: tt.maxKeywordMatches,
+			})
+			require.NoError(t, err)
+
+			keywordResult := result.KeywordResults[tt.keyword]
+			require.Empty(t, keywordResult.Error)
+			require.Equal(t, tt.expectedCount, keywordResult.Count)
+			require.Equal(t, tt.expectedMatches, keywordResult.Matches)
+		})
+	}
+}
+
+func TestClient_ExtractKeywordStemming(t *testing.T) {
+	tests := []struct {
+		name              string
+		body              string
+		keyword           string
+		keywordOptions    extractor.KeywordOptions
+		language          string
+		maxKeywordMatches int
+		expectedCount     int
+		expectedLanguage  string
+		expectedMatches   []extractor.KeywordMatch
+		expectedError     string
+	}{
+		{
+			name:              "matches inflections of the words, skipping stopwords",
+			body:              `<html lang="en-US"><body><p>Running shoes for the runner.</p><p>He runs in the shoes of a champion. Run, shoe!</p></body></html>`,
+			keyword:           "running shoes",
+			maxKeywordMatches: 10,
+			expectedCount:     3,
+			expectedLanguage:  "en",
+			expectedMatches: []extractor.KeywordMatch{
+				{Offset: 0, Length: 13, Snippet: "Running shoes for the runner. He runs in the shoes of", Path: "html > body > p:nth-of-type(1)"},
+				{Offset: 33, Length: 17, Snippet: "Running shoes for the runner. He runs in the shoes of a champion. Run, shoe!", Path: "html > body > p:nth-of-type(2)"},
+				{Offset: 66, Length: 9, Snippet: "er. He runs in the shoes of a champion. Run, shoe!", Path: "html > body > p:nth-of-type(2)"},
+			},
+		},
+		{
+			name:             "takes the language from the lang attribute",
+			body:             `<html lang="fr"><body><p>Les chanteuses chantent. Une chanteuse chante.</p></body></html>`,
+			keyword:          "chanteuse",
+			expectedCount:    2,
+			expectedLanguage: "fr",
+		},
+		{
+			name:             "detects the language from the text without a lang attribute",
+			body:             `<html><body><p>Die Häuser und das Haus sind in der Stadt.</p></body></html>`,
+			keyword:          "Häuser",
+			expectedCount:    2,
+			expectedLanguage: "de",
+		},
+		{
+			name:             "detects the language from the text when the lang attribute isn't supported",
+			body:             `<html lang="ja"><body><p>Die Häuser und das Haus sind in der Stadt.</p></body></html>`,
+			keyword:          "haus",
+			expectedCount:    2,
+			expectedLanguage: "de",
+		},
+		{
+			name:             "falls back to english",
+			body:             `<html><body><p>Runners</p></body></html>`,
+			keyword:          "runner",
+			expectedCount:    1,
+			expectedLanguage: "en",
+		},
+		{
+			name:             "uses the language of the options over the page",
+			body:             `<html lang="en"><body><p>Les chanteuses chantent.</p></body></html>`,
+			keyword:          "chanteuse",
+			language:         "fr",
+			expectedCount:    1,
+			expectedLanguage: "fr",
+		},
+		{
+			name:             "folds diacritics after stemming",
+			body:             `<html lang="fr"><body><p>Une crème brûlée, des crèmes.</p></body></html>`,
+			keyword:          "creme",
+			keywordOptions:   extractor.KeywordOptions{FoldDiacritics: true},
+			expectedCount:    2,
+			expectedLanguage: "fr",
+		},
+		{
+			name:             "reports keywords only made of stopwords",
+			body:             `<html lang="en"><body><p>The cat and the dog.</p></body></html>`,
+			keyword:          "the and",
+			expectedLanguage: "en",
+			expectedError:    "invalid keyword: only made of en stopwords",
+		},
+		{
+			name:             "reports unsupported languages",
+			body:             `<html><body><p>Runners</p></body></html>`,
+			keyword:          "runner",
+			language:         "xx",
+			expectedLanguage: "xx",
+			expectedError:    `unsupported language: "xx"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := &http.Client{
+				Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+						Body:       io.NopCloser(strings.NewReader(tt.body)),
+						Request:    r,
+					}, nil
+				}),
+			}
+			client := extractor.NewExtractorClient(httpClient, &mockCache{}, extractor.RetryPolicy{}, extractor.NewCircuitBreaker(extractor.BreakerPolicy{}), 0)
+
+			keywordOptions := tt.keywordOptions
+			keywordOptions.Mode = extractor.KeywordModeStemmed
+			result, err := client.Extract(context.Background(), "http://example.com", []string{tt.keyword}, extractor.Options{
+				KeywordOptions:    map[string]extractor.KeywordOptions{tt.keyword: keywordOptions},
+				MaxKeywordMatches: tt.maxKeywordMatches,
+				Language:          tt.language,
+			})
+			require.NoError(t, err)
+
+			keywordResult := result.KeywordResults[tt.keyword]
+			require.Equal(t, tt.expectedError, keywordResult.Error)
+			require.Equal(t, tt.expectedCount, keywordResult.Count)
+			require.Equal(t, tt.expectedLanguage, keywordResult.Language)
+			require.True(t, keywordResult.Options.CaseInsensitive)
+			require.Equal(t, tt.expectedMatches, keywordResult.Matches)
+		})
+	}
+}
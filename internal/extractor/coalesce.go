@@ -0,0 +1,92 @@
+package extractor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jponc/domain-crawler/internal/metrics"
+)
+
+// inflightFetch is a fetch shared by every caller asking for the same URL while it runs
+type inflightFetch struct {
+	done    chan struct{}
+	doc     document
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// coalescer makes concurrent callers for the same key share a single fetch and its result.
+// The shared fetch isn't tied to any single caller, it's only cancelled once every caller gave up on it.
+// It keeps the deadline of the caller which started it so retries don't outlast it.
+type coalescer struct {
+	mu    sync.Mutex
+	calls map[string]*inflightFetch
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{
+		calls: make(map[string]*inflightFetch),
+	}
+}
+
+func (g *coalescer) do(ctx context.Context, key string, fetch func(ctx context.Context) (document, error)) (document, error) {
+	g.mu.Lock()
+	call, ok := g.calls[key]
+	if ok {
+		call.waiters++
+		g.mu.Unlock()
+		metrics.ExtractorFetchesCoalesced.Inc()
+	} else {
+		var fetchCtx context.Context
+		var cancel context.CancelFunc
+		if deadline, ok := ctx.Deadline(); ok {
+			fetchCtx, cancel = context.WithDeadline(context.WithoutCancel(ctx), deadline)
+		} else {
+			fetchCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+		}
+		call = &inflightFetch{
+			done:    make(chan struct{}),
+			waiters: 1,
+			cancel:  cancel,
+		}
+		g.calls[key] = call
+		g.mu.Unlock()
+		metrics.ExtractorFetches.Inc()
+
+		go func() {
+			defer cancel()
+			call.doc, call.err = fetch(fetchCtx)
+
+			g.mu.Lock()
+			g.forget(key, call)
+			g.mu.Unlock()
+
+			close(call.done)
+		}()
+	}
+
+	select {
+	case <-call.done:
+		return call.doc, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		// Abort the fetch when nobody is waiting for it anymore
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			g.forget(key, call)
+		}
+
+		return document{}, ctx.Err()
+	}
+}
+
+// forget stops new callers from joining the call, the caller must hold g.mu
+func (g *coalescer) forget(key string, call *inflightFetch) {
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
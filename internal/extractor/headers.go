@@ -0,0 +1,47 @@
+package extractor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+)
+
+// hasHeaderOverrides tells whether the fetch sends its own headers, the response may then differ from the shared one
+func (o Options) hasHeaderOverrides() bool {
+	return o.UserAgent != "" || len(o.Headers) > 0
+}
+
+// setHeaders sets the overrides on the request, they take precedence over the defaults of the HTTP client
+func (o Options) setHeaders(req *http.Request) {
+	for k, v := range o.Headers {
+		req.Header.Set(k, v)
+	}
+	if o.UserAgent != "" {
+		req.Header.Set("User-Agent", o.UserAgent)
+	}
+}
+
+// headersKeySuffix keeps fetches with different overrides from sharing a fetch.
+// The overrides are hashed so credentials they may hold don't show up in keys.
+func (o Options) headersKeySuffix() string {
+	if !o.hasHeaderOverrides() {
+		return ""
+	}
+
+	header := http.Header{}
+	o.setHeaders(&http.Request{Header: header})
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		h.Write([]byte(name + ":" + header.Get(name) + "\n"))
+	}
+
+	return " headers=" + hex.EncodeToString(h.Sum(nil)[:8])
+}
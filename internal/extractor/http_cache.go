@@ -0,0 +1,185 @@
+package extractor
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// heuristicFreshnessFactor is the share of a document's age used as its freshness lifetime when
+	// the origin only sends Last-Modified, as suggested by RFC 9111
+	heuristicFreshnessFactor = 10
+	maxHeuristicFreshness    = 24 * time.Hour
+)
+
+// cachedResponse is what gets stored in the cache for every fetched document
+type cachedResponse struct {
+	Body         string    `json:"body"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	StoredAt     time.Time `json:"stored_at"`
+	// FreshUntil is zero when the origin gave no freshness information,
+	// such documents are served from cache until the cache backend evicts them
+	FreshUntil time.Time `json:"fresh_until,omitempty"`
+	// FinalURL and Redirects describe the redirect chain the document was reached through
+	FinalURL  string     `json:"final_url,omitempty"`
+	Redirects []Redirect `json:"redirects,omitempty"`
+	// Charset is the encoding the page was served in, Body is stored transcoded to UTF-8
+	Charset string `json:"charset,omitempty"`
+}
+
+// cacheDirectives are the Cache-Control directives we act upon
+type cacheDirectives struct {
+	noStore bool
+	noCache bool
+	// private responses are meant for a single user, e.g. pages fetched with credentials, a shared cache must not store them
+	private bool
+	maxAge  *time.Duration
+}
+
+func parseCacheControl(header string) cacheDirectives {
+	directives := cacheDirectives{}
+	for _, directive := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			directives.noStore = true
+		case "no-cache":
+			directives.noCache = true
+		case "private":
+			directives.private = true
+		case "max-age", "s-maxage":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil {
+				continue
+			}
+
+			// s-maxage takes precedence over max-age for shared caches like ours
+			maxAge := time.Duration(seconds) * time.Second
+			if directives.maxAge == nil || strings.EqualFold(name, "s-maxage") {
+				directives.maxAge = &maxAge
+			}
+		}
+	}
+
+	return directives
+}
+
+// storable tells whether a shared cache like ours may store the response
+func (d cacheDirectives) storable() bool {
+	return !d.noStore && !d.private
+}
+
+// newCachedResponse builds a cache entry from the response headers, it returns false when the response must not be stored
+func newCachedResponse(header http.Header, body string, now time.Time) (*cachedResponse, bool) {
+	directives := parseCacheControl(header.Get("Cache-Control"))
+	if !directives.storable() {
+		return nil, false
+	}
+
+	entry := &cachedResponse{
+		Body:     body,
+		StoredAt: now,
+	}
+	entry.updateFreshness(header, directives, now)
+
+	return entry, true
+}
+
+// updateFreshness refreshes the validators and freshness lifetime, e.g. after a 304 Not Modified
+func (e *cachedResponse) updateFreshness(header http.Header, directives cacheDirectives, now time.Time) {
+	if etag := header.Get("ETag"); etag != "" {
+		e.ETag = etag
+	}
+
+	if lastModified := header.Get("Last-Modified"); lastModified != "" {
+		e.LastModified = lastModified
+	}
+
+	e.StoredAt = now
+	e.FreshUntil = time.Time{}
+
+	switch {
+	case directives.noCache:
+		// Stored, but revalidated before every use
+		e.FreshUntil = now
+	case directives.maxAge != nil:
+		e.FreshUntil = now.Add(*directives.maxAge)
+	case header.Get("Expires") != "":
+		expires, err := http.ParseTime(header.Get("Expires"))
+		if err != nil {
+			// Invalid dates like "0" mean already expired
+			e.FreshUntil = now
+			break
+		}
+
+		// Use the origin clock to work out the lifetime when it tells us its time
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			date = now
+		}
+		e.FreshUntil = now.Add(expires.Sub(date))
+	case e.LastModified != "":
+		lastModified, err := http.ParseTime(e.LastModified)
+		if err != nil {
+			break
+		}
+
+		lifetime := now.Sub(lastModified) / heuristicFreshnessFactor
+		if lifetime > maxHeuristicFreshness {
+			lifetime = maxHeuristicFreshness
+		}
+		e.FreshUntil = now.Add(lifetime)
+	}
+}
+
+func (e *cachedResponse) isFresh(now time.Time) bool {
+	return e.FreshUntil.IsZero() || now.Before(e.FreshUntil)
+}
+
+// setConditionalHeaders asks the origin to only send the body when it changed since it was cached
+func (e *cachedResponse) setConditionalHeaders(req *http.Request) {
+	if e.ETag != "" {
+		req.Header.Set("If-None-Match", e.ETag)
+	}
+
+	if e.LastModified != "" {
+		req.Header.Set("If-Modified-Since", e.LastModified)
+	}
+}
+
+func (e *cachedResponse) hasValidators() bool {
+	return e.ETag != "" || e.LastModified != ""
+}
+
+// document returns the cached document, entries stored before redirects were recorded were served from url
+func (e *cachedResponse) document(url string, source FetchSource) document {
+	finalURL := e.FinalURL
+	if finalURL == "" {
+		finalURL = url
+	}
+
+	return document{html: e.Body, source: source, storedAt: e.StoredAt, finalURL: finalURL, redirects: e.Redirects, charset: e.Charset}
+}
+
+func encodeCachedResponse(e *cachedResponse) (string, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func decodeCachedResponse(v string) (*cachedResponse, error) {
+	e := &cachedResponse{}
+	err := json.Unmarshal([]byte(v), e)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
@@ -0,0 +1,460 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// KeywordMode selects how a keyword is matched against the text of a page
+type KeywordMode string
+
+const (
+	// KeywordModeSubstring counts every occurrence of the keyword, even inside longer words, it is the default
+	KeywordModeSubstring KeywordMode = "substring"
+	// KeywordModeWholeWord only counts occurrences which aren't part of a longer word, Unicode letters, marks,
+	// numbers and connectors such as _ being word characters
+	KeywordModeWholeWord KeywordMode = "whole_word"
+	// KeywordModeRegex treats the keyword as an RE2 regular expression and counts its matches
+	KeywordModeRegex KeywordMode = "regex"
+	// KeywordModeStemmed matches the words of the keyword with any of their inflections, e.g. "run" matches
+	// "running" and "runs", in the language of the page. It is always case-insensitive and ignores stopwords.
+	KeywordModeStemmed KeywordMode = "stemmed"
+)
+
+const (
+	// maxRegexLength and maxRegexInstructions keep regular expressions cheap to match, RE2 runs in time linear
+	// to the size of the text times the size of the compiled program
+	maxRegexLength       = 512
+	maxRegexInstructions = 5000
+	// maxRegexMatches bounds the memory used to count the matches of a regular expression on a page
+	maxRegexMatches = 100000
+	// regexMatchTimeout is how long the matches of a regular expression in the regions of a page are waited for
+	regexMatchTimeout = 250 * time.Millisecond
+)
+
+// ErrInvalidKeyword is returned for keywords which can't be matched with their options, e.g. a malformed regular expression
+var ErrInvalidKeyword = errors.New("invalid keyword")
+
+// KeywordOptions controls how a single keyword is matched, the zero value is a case-sensitive substring match.
+// Both the keyword and the text are normalized to NFC, so composed and decomposed accents match each other.
+type KeywordOptions struct {
+	Mode            KeywordMode
+	CaseInsensitive bool
+	// FoldDiacritics ignores accents and other combining marks, e.g. "cafe" matches "café"
+	FoldDiacritics bool
+}
+
+// KeywordResult is the count of a keyword by region of the page along with the options it was matched with
+type KeywordResult struct {
+	// Count is the number of occurrences in the visible text of the body, the same as Regions.Body
+	Count   int
+	Regions KeywordRegions
+	// Density is Count as a percentage of the words of the visible text of the body
+	Density float64
+	Options KeywordOptions
+	// Language is the language the keyword was stemmed in, only set for KeywordModeStemmed
+	Language string
+	// Matches holds the first matches in the visible text of the body, only set when they are asked for
+	Matches []KeywordMatch
+	// Error is set when the keyword couldn't be matched in full, the counts then hold the matches found until then
+	Error string
+}
+
+// KeywordRegions counts the occurrences of a keyword by region of the page. Regions overlap, e.g. headings
+// and anchor text are part of the body too.
+type KeywordRegions struct {
+	Title           int
+	MetaDescription int
+	H1              int
+	H2              int
+	H3              int
+	H4              int
+	H5              int
+	H6              int
+	// Body is the visible text of the body, without the content of scripts, styles and noscript elements
+	Body       int
+	AnchorText int
+	ImageAlt   int
+	// URLPath is the path of the URL the page was served from, with /, -, _, . and + read as spaces
+	URLPath int
+}
+
+// ValidateKeyword returns ErrInvalidKeyword when the keyword can't be matched with opts
+func ValidateKeyword(keyword string, opts KeywordOptions) error {
+	_, err := newKeywordMatcher(keyword, opts)
+	return err
+}
+
+// keywordMatcher counts the matches of a keyword in text prepared with prepare
+type keywordMatcher struct {
+	opts    KeywordOptions
+	keyword string
+	pattern string
+	re      *regexp.Regexp
+	// analyzer and terms are set by useAnalyzer for stemmed keywords, terms being the analyzed keyword
+	analyzer *analyzer
+	terms    []string
+	// wordStart and wordEnd tell whether the keyword starts and ends with a word character, only those edges need a boundary
+	wordStart bool
+	wordEnd   bool
+}
+
+func newKeywordMatcher(keyword string, opts KeywordOptions) (*keywordMatcher, error) {
+	if opts.Mode == "" {
+		opts.Mode = KeywordModeSubstring
+	}
+	m := &keywordMatcher{opts: opts, keyword: keyword}
+
+	switch opts.Mode {
+	case KeywordModeSubstring, KeywordModeWholeWord:
+		m.pattern = m.prepare(keyword)
+		first, _ := utf8.DecodeRuneInString(m.pattern)
+		last, _ := utf8.DecodeLastRuneInString(m.pattern)
+		m.wordStart = isWordRune(first)
+		m.wordEnd = isWordRune(last)
+	case KeywordModeRegex:
+		re, err := compileKeywordRegex(keyword, opts)
+		if err != nil {
+			return nil, err
+		}
+		m.re = re
+	case KeywordModeStemmed:
+		// Stemmed words are lowercased whatever the options
+		m.opts.CaseInsensitive = true
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidKeyword, opts.Mode)
+	}
+
+	return m, nil
+}
+
+// compileKeywordRegex compiles the keyword as a regular expression, rejecting those too large to be matched cheaply
+func compileKeywordRegex(keyword string, opts KeywordOptions) (*regexp.Regexp, error) {
+	if len(keyword) > maxRegexLength {
+		return nil, fmt.Errorf("%w: regular expression longer than %d bytes", ErrInvalidKeyword, maxRegexLength)
+	}
+
+	// Accents are removed from the text when folding diacritics, so they have to be removed from the pattern as well
+	pattern := normalizeText(keyword, opts.FoldDiacritics, false)
+	if opts.CaseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKeyword, err)
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKeyword, err)
+	}
+	if len(prog.Inst) > maxRegexInstructions {
+		return nil, fmt.Errorf("%w: regular expression too complex", ErrInvalidKeyword)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKeyword, err)
+	}
+
+	return re, nil
+}
+
+// useAnalyzer analyzes the keyword in the language of the analyzer, stemmed keywords can't be matched before
+func (m *keywordMatcher) useAnalyzer(a *analyzer) error {
+	m.analyzer = a
+	m.terms = []string{}
+	for _, t := range a.analyze(norm.NFC.String(m.keyword), m.opts.FoldDiacritics) {
+		m.terms = append(m.terms, t.term)
+	}
+
+	if len(m.terms) == 0 {
+		return fmt.Errorf("%w: only made of %s stopwords", ErrInvalidKeyword, a.code)
+	}
+	return nil
+}
+
+// textForm is how text is normalized before being matched
+type textForm struct {
+	foldDiacritics bool
+	lower          bool
+}
+
+// form is the normalization the matcher expects, regular expressions handle case themselves
+func (m *keywordMatcher) form() textForm {
+	// Stemmed keywords are matched on words analyzed one by one
+	if m.opts.Mode == KeywordModeStemmed {
+		return textForm{}
+	}
+
+	return textForm{
+		foldDiacritics: m.opts.FoldDiacritics,
+		lower:          m.opts.CaseInsensitive && m.opts.Mode != KeywordModeRegex,
+	}
+}
+
+// prepare normalizes text the way the matcher expects it
+func (m *keywordMatcher) prepare(text string) string {
+	form := m.form()
+	return normalizeText(text, form.foldDiacritics, form.lower)
+}
+
+// count returns the number of non-overlapping matches in text, which must have been prepared with prepare
+func (m *keywordMatcher) count(ctx context.Context, text string) (int, error) {
+	switch m.opts.Mode {
+	case KeywordModeWholeWord:
+		return len(m.findLiteral(text, -1)), nil
+	case KeywordModeStemmed:
+		return len(findTerms(m.analyzer.analyze(text, m.opts.FoldDiacritics), m.terms, -1)), nil
+	case KeywordModeRegex:
+		matches, err := m.findRegex(ctx, text, maxRegexMatches)
+		if err == nil && len(matches) == maxRegexMatches {
+			err = fmt.Errorf("stopped counting after %d matches", maxRegexMatches)
+		}
+		return len(matches), err
+	}
+
+	return strings.Count(text, m.pattern), nil
+}
+
+// find returns the bounds of the first n non-overlapping matches in text, which must have been prepared with prepare.
+// Every match is returned when n is negative.
+func (m *keywordMatcher) find(ctx context.Context, text string, n int) ([][]int, error) {
+	switch m.opts.Mode {
+	case KeywordModeRegex:
+		return m.findRegex(ctx, text, n)
+	case KeywordModeStemmed:
+		return findTerms(m.analyzer.analyze(text, m.opts.FoldDiacritics), m.terms, n), nil
+	}
+
+	return m.findLiteral(text, n), nil
+}
+
+// findLiteral finds the pattern as a substring or a whole word
+func (m *keywordMatcher) findLiteral(text string, n int) [][]int {
+	if m.pattern == "" {
+		return nil
+	}
+
+	matches := [][]int{}
+	for i := 0; i < len(text) && len(matches) != n; {
+		j := strings.Index(text[i:], m.pattern)
+		if j < 0 {
+			break
+		}
+
+		start, end := i+j, i+j+len(m.pattern)
+		if m.opts.Mode == KeywordModeWholeWord && !m.isWholeWord(text, start, end) {
+			// The next occurrence may start inside this one, e.g. "aa" in "aaa a"
+			_, size := utf8.DecodeRuneInString(text[start:])
+			i = start + size
+			continue
+		}
+
+		matches = append(matches, []int{start, end})
+		i = end
+	}
+
+	return matches
+}
+
+// isWholeWord tells whether text[start:end] isn't glued to the word characters around it
+func (m *keywordMatcher) isWholeWord(text string, start, end int) bool {
+	if m.wordStart && start > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(text[:start]); isWordRune(r) {
+			return false
+		}
+	}
+	if m.wordEnd && end < len(text) {
+		if r, _ := utf8.DecodeRuneInString(text[end:]); isWordRune(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// findRegex finds the matches of the regular expression, giving up once ctx is done.
+// RE2 can't be interrupted, but it runs in linear time so an abandoned match finishes shortly after.
+func (m *keywordMatcher) findRegex(ctx context.Context, text string, n int) ([][]int, error) {
+	found := make(chan [][]int, 1)
+	go func() {
+		found <- m.re.FindAllStringIndex(text, n)
+	}()
+
+	select {
+	case matches := <-found:
+		return matches, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("regular expression took longer than %s", regexMatchTimeout)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// countRegions counts the matches in every region, which must have been prepared with prepare, stopping at the first error
+func (m *keywordMatcher) countRegions(ctx context.Context, regions pageRegions) (KeywordRegions, error) {
+	counts := KeywordRegions{}
+	targets := []struct {
+		count *int
+		text  string
+	}{
+		{&counts.Body, regions.body},
+		{&counts.Title, regions.title},
+		{&counts.MetaDescription, regions.metaDescription},
+		{&counts.H1, regions.headings[0]},
+		{&counts.H2, regions.headings[1]},
+		{&counts.H3, regions.headings[2]},
+		{&counts.H4, regions.headings[3]},
+		{&counts.H5, regions.headings[4]},
+		{&counts.H6, regions.headings[5]},
+		{&counts.AnchorText, regions.anchorText},
+		{&counts.ImageAlt, regions.imageAlt},
+		{&counts.URLPath, regions.urlPath},
+	}
+
+	for _, target := range targets {
+		count, err := m.count(ctx, target.text)
+		if err != nil {
+			return counts, err
+		}
+		*target.count = count
+	}
+
+	return counts, nil
+}
+
+// prepareRegions normalizes the text of every region the way the matcher expects it
+func (m *keywordMatcher) prepareRegions(regions pageRegions) pageRegions {
+	prepared := pageRegions{
+		title:           m.prepare(regions.title),
+		metaDescription: m.prepare(regions.metaDescription),
+		body:            m.prepare(regions.body),
+		anchorText:      m.prepare(regions.anchorText),
+		imageAlt:        m.prepare(regions.imageAlt),
+		urlPath:         m.prepare(regions.urlPath),
+	}
+	for level, heading := range regions.headings {
+		prepared.headings[level] = m.prepare(heading)
+	}
+
+	return prepared
+}
+
+// getKeywordResults matches every keyword against the regions of the page, keywords without options are counted as substrings.
+// wordCount is the number of words of the visible text of the body the density is relative to, and up to maxMatches
+// matches of every keyword in it are returned. Stemmed keywords are analyzed in lang.
+func getKeywordResults(ctx context.Context, regions pageRegions, wordCount int, keywords []string, keywordOptions map[string]KeywordOptions, maxMatches int, lang string) map[string]KeywordResult {
+	results := map[string]KeywordResult{}
+	// The regions are prepared once for every combination of options used, and analyzed once for every stemmed keyword
+	prepared := map[textForm]pageRegions{}
+	mapped := map[textForm]mappedText{}
+	var stemAnalyzer *analyzer
+
+	for _, keyword := range keywords {
+		matcher, err := newKeywordMatcher(keyword, keywordOptions[keyword])
+		if err != nil {
+			results[keyword] = KeywordResult{Options: keywordOptions[keyword], Error: err.Error()}
+			continue
+		}
+
+		if matcher.opts.Mode == KeywordModeStemmed {
+			if stemAnalyzer == nil {
+				stemAnalyzer, err = newAnalyzer(lang)
+			}
+			if err == nil {
+				err = matcher.useAnalyzer(stemAnalyzer)
+			}
+			if err != nil {
+				results[keyword] = KeywordResult{Options: matcher.opts, Language: lang, Error: err.Error()}
+				continue
+			}
+		}
+
+		preparedRegions, exists := prepared[matcher.form()]
+		if !exists {
+			preparedRegions = matcher.prepareRegions(regions)
+			prepared[matcher.form()] = preparedRegions
+		}
+
+		// The timeout covers every region of the page along with the matches
+		matchCtx, cancel := context.WithTimeout(ctx, regexMatchTimeout)
+		counts, err := matcher.countRegions(matchCtx, preparedRegions)
+
+		result := KeywordResult{
+			Count:   counts.Body,
+			Regions: counts,
+			Density: keywordDensity(counts.Body, wordCount),
+			Options: matcher.opts,
+		}
+		if matcher.analyzer != nil {
+			result.Language = matcher.analyzer.code
+		}
+
+		if err == nil && maxMatches > 0 {
+			// Matches are found in the body prepared in a way offsets can be mapped back to the original text
+			mappedBody, exists := mapped[matcher.form()]
+			if !exists {
+				mappedBody = matcher.prepareMapped(regions.body)
+				mapped[matcher.form()] = mappedBody
+			}
+
+			var bounds [][]int
+			bounds, err = matcher.find(matchCtx, mappedBody.text, maxMatches)
+			if err == nil {
+				result.Matches = getKeywordMatches(regions, mappedBody, bounds)
+			}
+		}
+		cancel()
+
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results[keyword] = result
+	}
+
+	return results
+}
+
+// keywordDensity is count as a percentage of wordCount, rounded to two decimals
+func keywordDensity(count, wordCount int) float64 {
+	if wordCount == 0 {
+		return 0
+	}
+
+	return math.Round(float64(count)/float64(wordCount)*10000) / 100
+}
+
+// normalizeText normalizes s to NFC, removing combining marks when folding diacritics and case folding when lower is set
+func normalizeText(s string, foldDiacritics, lower bool) string {
+	if foldDiacritics {
+		s, _, _ = transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	} else {
+		s = norm.NFC.String(s)
+	}
+
+	if lower {
+		s = cases.Fold().String(s)
+	}
+
+	return s
+}
+
+// isWordRune tells whether r is part of a word, so a whole word match can't start or end next to it
+func isWordRune(r rune) bool {
+	return unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.Pc)
+}
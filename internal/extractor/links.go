@@ -0,0 +1,94 @@
+package extractor
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/jponc/domain-crawler/internal/utils"
+)
+
+// qualifyingRels are the rel values telling search engines how a link relates to the page
+var qualifyingRels = map[string]bool{
+	"nofollow":  true,
+	"sponsored": true,
+	"ugc":       true,
+}
+
+// extractLinks returns the http(s) links of the page resolved against its <base href>, normalized and deduplicated.
+// The anchor text and rel values of the first occurrence of a link are kept.
+func extractLinks(doc *goquery.Document, pageURL string, stripTrackingParams bool) []Link {
+	base := pageURL
+	if href, exists := doc.Find("base[href]").First().Attr("href"); exists {
+		if resolved, ok := utils.ResolveURL(pageURL, href); ok {
+			base = resolved
+		}
+	}
+
+	pageHost := hostname(pageURL)
+	pageDomain := utils.RegistrableDomain(pageURL)
+
+	links := []Link{}
+	seen := map[string]bool{}
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		if href == "" {
+			return
+		}
+
+		linkURL, ok := utils.ResolveURL(base, href)
+		if !ok {
+			return
+		}
+
+		linkURL = utils.NormalizeURL(linkURL)
+		if stripTrackingParams {
+			linkURL = utils.StripTrackingParams(linkURL)
+		}
+
+		if seen[linkURL] {
+			return
+		}
+		seen[linkURL] = true
+
+		links = append(links, Link{
+			URL:   linkURL,
+			Text:  strings.Join(strings.Fields(s.Text()), " "),
+			Rel:   parseRel(s.AttrOr("rel", "")),
+			Class: classifyLink(linkURL, pageHost, pageDomain),
+		})
+	})
+
+	return links
+}
+
+func parseRel(rel string) []string {
+	values := []string{}
+	for _, value := range strings.Fields(strings.ToLower(rel)) {
+		if qualifyingRels[value] {
+			values = append(values, value)
+		}
+	}
+
+	return utils.RemoveDuplicates(values)
+}
+
+func classifyLink(linkURL string, pageHost string, pageDomain string) LinkClass {
+	switch {
+	case hostname(linkURL) == pageHost:
+		return LinkInternal
+	case utils.RegistrableDomain(linkURL) == pageDomain:
+		return LinkSubdomain
+	default:
+		return LinkExternal
+	}
+}
+
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,146 @@
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jponc/domain-crawler/internal/utils"
+)
+
+// defaultMaxRedirects matches the limit of the default http.Client
+const defaultMaxRedirects = 10
+
+var (
+	// ErrRedirectLoop is returned when a redirect points back to a URL already visited in the chain
+	ErrRedirectLoop = errors.New("redirect loop")
+	// ErrTooManyRedirects is returned when the chain is longer than RedirectPolicy.MaxRedirects
+	ErrTooManyRedirects = errors.New("too many redirects")
+	// ErrRedirectNotFollowed is returned when the origin answers with a redirect and RedirectPolicy.NoFollow is set
+	ErrRedirectNotFollowed = errors.New("redirect not followed")
+)
+
+// RedirectError is returned when a redirect chain is aborted, Redirects holds the hops seen so far
+type RedirectError struct {
+	Err       error
+	Redirects []Redirect
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("%s after %d redirect(s)", e.Err, len(e.Redirects))
+}
+
+func (e *RedirectError) Unwrap() error {
+	return e.Err
+}
+
+func (p RedirectPolicy) maxRedirects() int {
+	if p.MaxRedirects <= 0 {
+		return defaultMaxRedirects
+	}
+
+	return p.MaxRedirects
+}
+
+// allows tells whether a document reached through the given number of redirects satisfies the policy
+func (p RedirectPolicy) allows(redirects int) bool {
+	if p.NoFollow {
+		return redirects == 0
+	}
+
+	return redirects <= p.maxRedirects()
+}
+
+// keySuffix keeps fetches with different redirect policies from sharing an in-flight fetch
+func (p RedirectPolicy) keySuffix() string {
+	if p.NoFollow {
+		return " no-redirects"
+	}
+
+	if p.maxRedirects() != defaultMaxRedirects {
+		return fmt.Sprintf(" max-redirects=%d", p.maxRedirects())
+	}
+
+	return ""
+}
+
+// redirectTracker records the redirect chain of a single fetch through http.Client.CheckRedirect
+type redirectTracker struct {
+	policy    RedirectPolicy
+	redirects []Redirect
+	visited   map[string]bool
+	// err is set when the chain was aborted by the tracker
+	err error
+}
+
+func newRedirectTracker(policy RedirectPolicy, rawURL string) *redirectTracker {
+	return &redirectTracker{
+		policy:  policy,
+		visited: map[string]bool{utils.NormalizeURL(rawURL): true},
+	}
+}
+
+func (t *redirectTracker) checkRedirect(req *http.Request, via []*http.Request) error {
+	from := via[len(via)-1].URL
+	t.redirects = append(t.redirects, Redirect{
+		URL:        from.String(),
+		StatusCode: req.Response.StatusCode,
+		Location:   req.Response.Header.Get("Location"),
+		Downgrade:  from.Scheme == "https" && req.URL.Scheme == "http",
+	})
+
+	// The redirect response is returned to fetchFromOrigin, which reports it
+	if t.policy.NoFollow {
+		return http.ErrUseLastResponse
+	}
+
+	next := utils.NormalizeURL(req.URL.String())
+	if t.visited[next] {
+		t.err = ErrRedirectLoop
+		return t.err
+	}
+
+	if len(t.redirects) > t.policy.maxRedirects() {
+		t.err = ErrTooManyRedirects
+		return t.err
+	}
+
+	t.visited[next] = true
+	return nil
+}
+
+// abort returns the RedirectError for the chain
+func (t *redirectTracker) abort(err error) *RedirectError {
+	return &RedirectError{Err: err, Redirects: t.redirects}
+}
+
+// hasHTTPSDowngrade tells whether any hop of the chain went from https to http
+func hasHTTPSDowngrade(redirects []Redirect) bool {
+	for _, redirect := range redirects {
+		if redirect.Downgrade {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRedirect tells whether the status code is one http.Client follows
+func isRedirect(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+
+	return false
+}
+
+// finalURLOf returns the URL the document was served from, falling back to the requested URL
+func finalURLOf(res *http.Response, requested string) string {
+	if res.Request == nil || res.Request.URL == nil {
+		return requested
+	}
+
+	return res.Request.URL.String()
+}
@@ -0,0 +1,147 @@
+package extractor
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// hiddenElements hold no visible text
+var hiddenElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+}
+
+// blockElements break the text around them, e.g. two paragraphs don't glue their last and first words together
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Br: true,
+	atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true,
+	atom.Figure: true, atom.Footer: true, atom.Form: true, atom.H1: true, atom.H2: true,
+	atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.Header: true,
+	atom.Hr: true, atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true,
+	atom.Option: true, atom.P: true, atom.Pre: true, atom.Section: true, atom.Table: true,
+	atom.Td: true, atom.Th: true, atom.Tr: true, atom.Ul: true,
+}
+
+// urlPathSeparators are read as spaces in URL paths so "cat food" matches /cat-food
+var urlPathSeparators = strings.NewReplacer("/", " ", "-", " ", "_", " ", ".", " ", "+", " ")
+
+// pageRegions holds the text of every region of a page keywords are counted in
+type pageRegions struct {
+	title           string
+	metaDescription string
+	// headings holds the text of the H1 to H6 headings by level
+	headings [6]string
+	body     string
+	// bodyNodes are the text nodes of the body in the order their text appears in body
+	bodyNodes  []textNode
+	anchorText string
+	imageAlt   string
+	urlPath    string
+}
+
+// getPageRegions extracts the text of every region of the page, pageURL is the URL the page was served from
+func getPageRegions(doc *goquery.Document, pageURL string, title string, metaDescriptions []string) pageRegions {
+	regions := pageRegions{
+		title:           title,
+		metaDescription: strings.Join(metaDescriptions, "\n"),
+		anchorText:      visibleText(doc.Find("a").Nodes...),
+	}
+	regions.body, regions.bodyNodes = visibleTextNodes(doc.Find("body").Nodes...)
+
+	for level := range regions.headings {
+		regions.headings[level] = visibleText(doc.Find("h" + string(rune('1'+level))).Nodes...)
+	}
+
+	alts := []string{}
+	doc.Find("img[alt]").Each(func(i int, s *goquery.Selection) {
+		alts = append(alts, s.AttrOr("alt", ""))
+	})
+	regions.imageAlt = strings.Join(alts, "\n")
+
+	if u, err := url.Parse(pageURL); err == nil {
+		regions.urlPath = strings.TrimSpace(urlPathSeparators.Replace(u.Path))
+	}
+
+	return regions
+}
+
+// textNode is a text node along with the offset of its text in the visible text it is part of
+type textNode struct {
+	start int
+	node  *html.Node
+}
+
+// visibleText returns the text of the nodes as rendered, without the content of scripts and styles and with
+// block elements separated by line breaks
+func visibleText(nodes ...*html.Node) string {
+	text, _ := visibleTextNodes(nodes...)
+	return text
+}
+
+// visibleTextNodes returns the visible text of the nodes along with the text nodes it is made of
+func visibleTextNodes(nodes ...*html.Node) (string, []textNode) {
+	var b strings.Builder
+	textNodes := []textNode{}
+
+	// separate breaks the text unless it is empty or already ends with whitespace
+	separate := func() {
+		if s := b.String(); s != "" && !strings.HasSuffix(s, "\n") && !strings.HasSuffix(s, " ") {
+			b.WriteByte('\n')
+		}
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			textNodes = append(textNodes, textNode{start: b.Len(), node: n})
+			b.WriteString(n.Data)
+			return
+		case html.CommentNode, html.DoctypeNode:
+			return
+		case html.ElementNode:
+			if hiddenElements[n.DataAtom] {
+				return
+			}
+		}
+
+		block := n.Type == html.ElementNode && blockElements[n.DataAtom]
+		if block {
+			separate()
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			separate()
+		}
+	}
+
+	for _, n := range nodes {
+		separate()
+		walk(n)
+	}
+
+	return b.String(), textNodes
+}
+
+// countWords returns the number of words of text, words being runs of Unicode letters, marks, numbers and connectors
+func countWords(text string) int {
+	words := 0
+	inWord := false
+	for _, r := range text {
+		wordRune := isWordRune(r)
+		if wordRune && !inWord {
+			words++
+		}
+		inWord = wordRune
+	}
+
+	return words
+}
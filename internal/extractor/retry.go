@@ -0,0 +1,121 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed fetches from the origin are retried, the zero value doesn't retry
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, it doubles with every retry
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay, fetches the origin asks to retry after longer than it aren't retried
+	MaxDelay time.Duration
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	return max(p.MaxAttempts, 1)
+}
+
+// backoff returns the jittered delay before the given retry, counting from 1
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	// Past a few doublings the delay is capped anyway, bounding the shift keeps it from overflowing
+	delay := p.BaseDelay << min(retry-1, 16)
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	// Equal jitter keeps at least half of the delay while spreading concurrent retries
+	if half := delay / 2; half > 0 {
+		delay = half + rand.N(half)
+	}
+
+	return delay
+}
+
+// AttemptsError is returned when every attempt at fetching from the origin failed
+type AttemptsError struct {
+	// Errors holds the error of every attempt
+	Errors []string
+	// Err is the error of the last attempt, or the context error when it ended while waiting to retry
+	Err error
+	// BytesRead is the size of the body read by the last attempt before it failed, e.g. up to the size limit
+	BytesRead int64
+}
+
+func (e *AttemptsError) Error() string {
+	if len(e.Errors) <= 1 {
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("%s (after %d attempts)", e.Err, len(e.Errors))
+}
+
+func (e *AttemptsError) Unwrap() error {
+	return e.Err
+}
+
+// retryableError is a failed attempt worth retrying, retryAfter is the delay asked by the origin if any
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// isRetryableStatus tells whether the status is a transient server side failure
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// parseRetryAfter parses the delay-seconds or HTTP-date forms of a Retry-After header, it returns zero when unset or invalid
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	// Delays too long to be represented, even in seconds, are clamped rather than wrapped around to a short delay
+	if seconds, err := strconv.ParseInt(header, 10, 64); err == nil || errors.Is(err, strconv.ErrRange) {
+		if seconds > math.MaxInt64/int64(time.Second) {
+			return math.MaxInt64
+		}
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if at, err := http.ParseTime(header); err == nil {
+		return max(at.Sub(now), 0)
+	}
+
+	return 0
+}
+
+// waitForRetry sleeps for the delay, it returns false without waiting when the delay would outlast the context deadline
+func waitForRetry(ctx context.Context, delay time.Duration) (bool, error) {
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		return false, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
@@ -0,0 +1,244 @@
+package extractor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// snippetContext is the number of characters of text kept on each side of a match in its snippet
+	snippetContext = 40
+	// MaxKeywordMatches is the largest number of matches returned by keyword
+	MaxKeywordMatches = 100
+)
+
+// cssIdentifier matches ids which can be used in a CSS selector without escaping
+var cssIdentifier = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// KeywordMatch is where a keyword appears in the visible text of the body
+type KeywordMatch struct {
+	// Offset and Length are in characters, not bytes, of the visible text of the body
+	Offset int
+	Length int
+	// Snippet is the match along with the text around it, whitespace collapsed
+	Snippet string
+	// Path is the CSS path of the element holding the match, e.g. div#content > p:nth-of-type(2)
+	Path string
+}
+
+// shift records a run of the original text whose normalized form has a different length, e.g. "é" folded to "e"
+type shift struct {
+	prepared    int
+	preparedLen int
+	original    int
+	originalLen int
+}
+
+// mappedText is text normalized for a matcher which can tell where its offsets come from in the original text
+type mappedText struct {
+	text string
+	// shifts are sorted by offset, offsets between them map linearly to the original text
+	shifts []shift
+}
+
+// prepareMapped normalizes text like prepare, one normalization segment at a time so offsets can be mapped back
+func (m *keywordMatcher) prepareMapped(text string) mappedText {
+	var b strings.Builder
+	shifts := []shift{}
+	form := m.form()
+
+	for start := 0; start < len(text); {
+		end := nextSegment(text, start)
+		segment := text[start:end]
+
+		prepared := segment
+		switch {
+		case end-start == 1 && segment[0] < utf8.RuneSelf:
+			// Plain ASCII only changes when lowered, which keeps its length
+			if form.lower {
+				prepared = strings.ToLower(segment)
+			}
+		case form != textForm{} || !norm.NFC.IsNormalString(segment):
+			prepared = m.prepare(segment)
+		}
+
+		if len(prepared) != len(segment) {
+			shifts = append(shifts, shift{prepared: b.Len(), preparedLen: len(prepared), original: start, originalLen: len(segment)})
+		}
+		b.WriteString(prepared)
+		start = end
+	}
+
+	return mappedText{text: b.String(), shifts: shifts}
+}
+
+// nextSegment returns where the normalization segment starting at start ends, segments are normalized independently
+func nextSegment(text string, start int) int {
+	_, size := utf8.DecodeRuneInString(text[start:])
+	end := start + size
+	for end < len(text) && !norm.NFC.PropertiesString(text[end:]).BoundaryBefore() {
+		_, size = utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+
+	return end
+}
+
+// original maps an offset of the normalized text back to the original text, offsets inside a shifted run
+// are moved to its start, or to its end for end offsets, so the original run is kept whole
+func (t mappedText) original(offset int, isEnd bool) int {
+	i := sort.Search(len(t.shifts), func(i int) bool {
+		return t.shifts[i].prepared > offset
+	}) - 1
+	if i < 0 {
+		return offset
+	}
+
+	s := t.shifts[i]
+	switch {
+	case offset == s.prepared:
+		return s.original
+	case offset < s.prepared+s.preparedLen:
+		if isEnd {
+			return s.original + s.originalLen
+		}
+		return s.original
+	}
+
+	return s.original + s.originalLen + offset - s.prepared - s.preparedLen
+}
+
+// getKeywordMatches turns the bounds of matches in the prepared body into matches of the original body
+func getKeywordMatches(regions pageRegions, prepared mappedText, bounds [][]int) []KeywordMatch {
+	matches := make([]KeywordMatch, 0, len(bounds))
+
+	// Matches are in order, so characters are counted from the previous match on
+	chars, counted := 0, 0
+	for _, bound := range bounds {
+		start := prepared.original(bound[0], false)
+		end := prepared.original(bound[1], true)
+
+		chars += utf8.RuneCountInString(regions.body[counted:start])
+		counted = start
+
+		matches = append(matches, KeywordMatch{
+			Offset:  chars,
+			Length:  utf8.RuneCountInString(regions.body[start:end]),
+			Snippet: snippet(regions.body, start, end),
+			Path:    cssPath(matchElement(regions.bodyNodes, start, end)),
+		})
+	}
+
+	return matches
+}
+
+// snippet returns text[start:end] along with up to snippetContext characters on each side, whitespace collapsed
+func snippet(text string, start, end int) string {
+	for i := 0; i < snippetContext && start > 0; i++ {
+		_, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+	}
+	for i := 0; i < snippetContext && end < len(text); i++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+
+	return strings.Join(strings.Fields(text[start:end]), " ")
+}
+
+// matchElement returns the innermost element holding both ends of the match
+func matchElement(textNodes []textNode, start, end int) *html.Node {
+	if len(textNodes) == 0 {
+		return nil
+	}
+
+	first := textNodeAt(textNodes, start)
+	if end > start {
+		end--
+	}
+	last := textNodeAt(textNodes, end)
+
+	// Matches spanning inline elements, e.g. "key<b>word</b>", are held by their closest common ancestor
+	ancestors := map[*html.Node]bool{}
+	for n := first.Parent; n != nil; n = n.Parent {
+		ancestors[n] = true
+	}
+	for n := last.Parent; n != nil; n = n.Parent {
+		if ancestors[n] {
+			return n
+		}
+	}
+
+	return first.Parent
+}
+
+// textNodeAt returns the text node the text at offset belongs to, or the one before it for text between nodes
+func textNodeAt(textNodes []textNode, offset int) *html.Node {
+	i := sort.Search(len(textNodes), func(i int) bool {
+		return textNodes[i].start > offset
+	}) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return textNodes[i].node
+}
+
+// cssPath returns a CSS selector of the element, starting from its closest ancestor with an id or from the root
+func cssPath(n *html.Node) string {
+	parts := []string{}
+	for ; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if id := attr(n, "id"); cssIdentifier.MatchString(id) {
+			parts = append(parts, n.Data+"#"+id)
+			break
+		}
+
+		part := n.Data
+		if index, total := nthOfType(n); total > 1 {
+			part += fmt.Sprintf(":nth-of-type(%d)", index)
+		}
+		parts = append(parts, part)
+	}
+
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+
+	return strings.Join(parts, " > ")
+}
+
+// nthOfType returns the position of the element among its siblings of the same type, starting at 1, and their number
+func nthOfType(n *html.Node) (int, int) {
+	if n.Parent == nil {
+		return 1, 1
+	}
+
+	index, total := 0, 0
+	for sibling := n.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode || sibling.Data != n.Data {
+			continue
+		}
+		total++
+		if sibling == n {
+			index = total
+		}
+	}
+
+	return index, total
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+
+	return ""
+}
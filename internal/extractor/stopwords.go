@@ -0,0 +1,72 @@
+package extractor
+
+import "strings"
+
+// stopwordSet returns the set of the space separated words
+func stopwordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+
+	return set
+}
+
+// The stopwords are the most frequent function words of every language, they are neither matched nor stemmed
+// and also tell the language of pages without a lang attribute. Word fragments left by apostrophes, e.g. the
+// l of l'amour or the s of cat's, are stopwords too.
+
+var englishStopwords = stopwordSet(`
+	a about above after again against all am an and any are as at be because been before being below between
+	both but by can could did do does doing down during each few for from further had has have having he her
+	here hers herself him himself his how i if in into is it its itself just me more most my myself no nor
+	not of off on once only or other our ours ourselves out over own same she should so some such than that
+	the their theirs them themselves then there these they this those through to too under until up very
+	was we were what when where which while who whom why will with would you your yours yourself yourselves
+	s t d ll m re ve
+`)
+
+var frenchStopwords = stopwordSet(`
+	à au aux avec ce ces cette dans de des du elle elles en et eux il ils je la le les leur leurs lui ma mais
+	me même mes moi mon ne nos notre nous on ou où par pas pour qu que qui sa se ses son sur ta te tes toi
+	ton tu un une vos votre vous est sont été être avoir ai as avons avez ont était ceci cela ça y
+	c d j l m n s t
+`)
+
+var germanStopwords = stopwordSet(`
+	aber alle als also am an auch auf aus bei bin bis bist da damit dann das dass dein deine dem den der des
+	dessen die dies diese diesem diesen dieser dieses doch dort du durch ein eine einem einen einer eines er
+	es euer eure für hat hatte hatten hier ich ihr ihre im in ist ja jede jedem jeden jeder jedes kann kein
+	keine mein meine mit muss nach nicht noch nun nur ob oder ohne sehr sein seine sich sie sind so über um
+	und uns unser unter vom von vor war waren was weil wenn wer wie wir wird wo zu zum zur
+`)
+
+var spanishStopwords = stopwordSet(`
+	a al algo algunos ante antes como con contra cual cuando de del desde donde durante e el ella ellas
+	ellos en entre era es esa esas ese eso esos esta estas este esto estos fue fueron ha han hasta hay la las
+	le les lo los más me mi mis mucho muy nada ni no nos nosotros o otra otros para pero poco por porque que
+	quien se sea ser si sin sobre su sus también te tiene todo todos tu tus un una uno unos y ya yo
+`)
+
+var italianStopwords = stopwordSet(`
+	a ad agli ai al alla alle allo anche che chi ci come con contro da dagli dai dal dalla dalle dei del
+	della delle dello di dov dove e ed era erano è gli ha hanno ho i il in io la le lei lo loro lui ma mi mio
+	ne negli nei nel nella nelle nello noi non o per perché più quale quando quanto quella quelle quello
+	questa queste questo se sei si sia sono su sua sue sugli sui sul sulla suo tra tu tutti tutto un una uno
+	voi all dall dell nell sull l d c s un
+`)
+
+var dutchStopwords = stopwordSet(`
+	aan al alles als altijd andere ben bij daar dan dat de der deze die dit doch doen door dus een eens en er
+	ge geen geweest haar had heb hebben heeft hem het hier hij hoe hun iemand iets ik in is ja je kan kon
+	kunnen maar me meer men met mij mijn moet na naar niet niets nog nu of om omdat onder ons ook op over
+	reeds te tegen toch toen tot u uit uw van veel voor want waren was wat we wel werd wezen wie wij wil
+	worden wordt zal ze zelf zich zij zijn zo zonder zou
+`)
+
+var portugueseStopwords = stopwordSet(`
+	a à ao aos as às até com como da das de dela delas dele deles depois do dos e ela elas ele eles em
+	entre era essa essas esse esses esta estas este estes eu foi foram há isso isto já lhe lhes mais mas me
+	mesmo meu meus minha minhas muito na nas não nem no nos nós num numa o os ou para pela pelas pelo pelos
+	por quando que quem se sem ser seu seus só sua suas também te tem teu tua um uma umas uns você vocês
+`)
@@ -1,11 +1,123 @@
 package extractor
 
+import "time"
+
 type KeywordCounts map[string]int
 
+// FetchSource tells where the HTML document of a result came from
+type FetchSource string
+
+const (
+	// SourceCache is a fresh document served from cache without contacting the origin
+	SourceCache FetchSource = "cache"
+	// SourceRevalidated is a stale cached document the origin confirmed unchanged with a 304
+	SourceRevalidated FetchSource = "revalidated"
+	// SourceOrigin is a document downloaded from the origin
+	SourceOrigin FetchSource = "origin"
+)
+
+// CacheMode controls how the cache is used for a single extraction
+type CacheMode string
+
+const (
+	// CacheModeUse serves fresh cached documents and revalidates stale ones, it is the default
+	CacheModeUse CacheMode = "use"
+	// CacheModeRefresh always contacts the origin, revalidating the cached document when it can, and caches the result
+	CacheModeRefresh CacheMode = "refresh"
+	// CacheModeBypass downloads from the origin without reading or writing the cache
+	CacheModeBypass CacheMode = "bypass"
+)
+
+// CachePolicy is the per request cache behaviour
+type CachePolicy struct {
+	Mode CacheMode
+	// MaxAge additionally treats cached documents older than it as stale, only used by CacheModeUse
+	MaxAge *time.Duration
+}
+
+// RedirectPolicy is the per request redirect behaviour
+type RedirectPolicy struct {
+	// NoFollow fails the extraction with ErrRedirectNotFollowed when the origin answers with a redirect
+	NoFollow bool
+	// MaxRedirects is the longest redirect chain followed, zero uses the default of 10
+	MaxRedirects int
+}
+
+// Options are the per request extraction options
+type Options struct {
+	Cache     CachePolicy
+	Redirects RedirectPolicy
+	// StripTrackingParams removes analytics query parameters such as utm_source from extracted links
+	StripTrackingParams bool
+	// UserAgent and Headers replace the defaults of the HTTP client for this fetch.
+	// Such fetches neither read nor write the cache since the page may depend on them.
+	UserAgent string
+	Headers   map[string]string
+	// KeywordOptions holds the match options of keywords, keywords without options are matched as case-sensitive substrings
+	KeywordOptions map[string]KeywordOptions
+	// MaxKeywordMatches is the number of matches of every keyword returned along with their snippet and position,
+	// zero returns none and it is capped to MaxKeywordMatches
+	MaxKeywordMatches int
+	// Language is the ISO 639-1 code of the language stemmed keywords are analyzed in, it is detected from the
+	// page when empty
+	Language string
+}
+
+// LinkClass tells where a link points to relative to the page it was found on
+type LinkClass string
+
+const (
+	// LinkInternal points to the same host as the page
+	LinkInternal LinkClass = "internal"
+	// LinkSubdomain points to another host on the same registrable domain, e.g. blog.example.com from example.com
+	LinkSubdomain LinkClass = "subdomain"
+	// LinkExternal points to another registrable domain
+	LinkExternal LinkClass = "external"
+)
+
+// Link is an http(s) link found on a page, resolved and normalized
+type Link struct {
+	URL  string
+	Text string
+	// Rel holds the nofollow, sponsored and ugc values of the rel attribute
+	Rel   []string
+	Class LinkClass
+}
+
+// Redirect is a hop of a redirect chain
+type Redirect struct {
+	// URL answered with the redirect
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	// Location is the raw Location header
+	Location string `json:"location"`
+	// Downgrade is set when the redirect goes from https to http
+	Downgrade bool `json:"downgrade,omitempty"`
+}
+
 type ExtractResult struct {
-	URL              string
+	URL string
+	// FinalURL is the URL the document was served from once redirects were followed
+	FinalURL       string
+	Redirects      []Redirect
+	HTTPSDowngrade bool
+	// Attempts is the number of attempts at fetching from the origin, zero when served from cache
+	Attempts int
+	// AttemptErrors holds the errors of the failed attempts before the successful one
+	AttemptErrors []string
+	// BytesRead is the size of the body read from the origin, zero when served from cache or revalidated
+	BytesRead int64
+	// Charset is the encoding the page was served in before being transcoded to UTF-8
+	Charset          string
 	Title            string
 	MetaDescriptions []string
-	Links            []string
+	Links            []Link
 	KeywordCounts    KeywordCounts
+	// KeywordResults holds the count of every keyword along with the options it was matched with
+	KeywordResults map[string]KeywordResult
+	// WordCount is the number of words of the visible text of the body
+	WordCount int
+	Source    FetchSource
+	// CacheAge is the age of the cached document used, zero when Source is SourceOrigin
+	CacheAge time.Duration
 }
@@ -0,0 +1,177 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/urlpolicy"
+)
+
+// Options configures the HTTP client returned by New, zero durations and sizes keep the net/http defaults
+type Options struct {
+	// ConnectTimeout bounds establishing the TCP connection
+	ConnectTimeout time.Duration
+	// TLSHandshakeTimeout bounds the TLS handshake once connected
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout bounds waiting for the response headers once the request is written
+	ResponseHeaderTimeout time.Duration
+	// Timeout bounds the whole request, redirects and reading the body included
+	Timeout time.Duration
+	// UserAgent is sent with requests which don't set their own
+	UserAgent string
+	// Headers are sent with requests which don't set them
+	Headers map[string]string
+	// ProxyURL routes requests through an http://, https:// or socks5:// proxy, requests go direct when empty
+	// whatever the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables say
+	ProxyURL            string
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	// URLPolicy blocks requests to internal addresses, redirects included, every URL is allowed when nil
+	URLPolicy *urlpolicy.Policy
+}
+
+// New returns an HTTP client shared by every outgoing request of the service
+func New(opts Options) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Only the proxy configured explicitly is used, so whether requests are proxied is known when checking the URL policy
+	transport.Proxy = nil
+
+	dialer := &net.Dialer{
+		Timeout:   opts.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	// Checking the address actually dialed, once resolved, defeats DNS rebinding.
+	// Through a proxy the dialed address is the proxy's, targets are checked by policyTransport instead.
+	if opts.URLPolicy != nil && opts.ProxyURL == "" {
+		dialer.Control = opts.URLPolicy.Control
+	}
+	transport.DialContext = dialer.DialContext
+
+	if opts.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = opts.TLSHandshakeTimeout
+	}
+	if opts.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+	}
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+
+	if opts.ProxyURL != "" {
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy url: %w", err)
+		}
+
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme: %q", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	headers := http.Header{}
+	for k, v := range opts.Headers {
+		headers.Set(k, v)
+	}
+	if opts.UserAgent != "" {
+		headers.Set("User-Agent", opts.UserAgent)
+	}
+
+	var next http.RoundTripper = &defaultHeadersTransport{next: transport, headers: headers}
+	if opts.URLPolicy != nil {
+		next = &policyTransport{next: next, policy: opts.URLPolicy, resolve: opts.ProxyURL != ""}
+	}
+
+	return &http.Client{
+		Transport: next,
+		Timeout:   opts.Timeout,
+	}, nil
+}
+
+// policyTransport rejects requests to blocked URLs, every redirect goes through it as well.
+// When resolve is set the hostname is resolved and checked before the request is sent, which is
+// the best that can be done when the proxy resolves it.
+type policyTransport struct {
+	next    http.RoundTripper
+	policy  *urlpolicy.Policy
+	resolve bool
+}
+
+func (t *policyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.check(req); err != nil {
+		// RoundTrippers must close the request body, even when failing
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+func (t *policyTransport) check(req *http.Request) error {
+	if err := t.policy.CheckURL(req.URL.String()); err != nil {
+		return err
+	}
+
+	if !t.resolve {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(req.Context(), "ip", req.URL.Hostname())
+	if err != nil {
+		return fmt.Errorf("failed to resolve host: %w", err)
+	}
+
+	for _, addr := range addrs {
+		if err := t.policy.CheckIP(addr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// defaultHeadersTransport adds the default headers the request doesn't set itself
+type defaultHeadersTransport struct {
+	next    http.RoundTripper
+	headers http.Header
+}
+
+func (t *defaultHeadersTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	missing := false
+	for k := range t.headers {
+		if _, ok := req.Header[k]; !ok {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return t.next.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the request, the headers are set on a copy
+	req = req.Clone(req.Context())
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	for k, v := range t.headers {
+		if _, ok := req.Header[k]; !ok {
+			req.Header[k] = v
+		}
+	}
+
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,209 @@
+package httpclient_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/httpclient"
+	"github.com/jponc/domain-crawler/internal/urlpolicy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_DefaultHeaders(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	}))
+	defer server.Close()
+
+	client, err := httpclient.New(httpclient.Options{
+		UserAgent: "domaincrawler/1.0",
+		Headers:   map[string]string{"Accept-Language": "en", "X-Team": "crawl"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		headers         map[string]string
+		expectedHeaders map[string]string
+	}{
+		{
+			name:            "sends the default user agent and headers",
+			expectedHeaders: map[string]string{"User-Agent": "domaincrawler/1.0", "Accept-Language": "en", "X-Team": "crawl"},
+		},
+		{
+			name:            "keeps the headers set by the request",
+			headers:         map[string]string{"User-Agent": "custom", "Accept-Language": "fr"},
+			expectedHeaders: map[string]string{"User-Agent": "custom", "Accept-Language": "fr", "X-Team": "crawl"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			res, err := client.Do(req)
+			require.NoError(t, err)
+			res.Body.Close()
+
+			for k, v := range tt.expectedHeaders {
+				require.Equal(t, v, received.Get(k), k)
+			}
+
+			// The caller's request isn't modified
+			require.Len(t, req.Header, len(tt.headers))
+		})
+	}
+}
+
+func TestNew_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	client, err := httpclient.New(httpclient.Options{ProxyURL: proxy.URL})
+	require.NoError(t, err)
+
+	res, err := client.Get("http://example.com/page")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, "http://example.com/page", proxied)
+
+	_, err = httpclient.New(httpclient.Options{ProxyURL: "ftp://proxy:21"})
+	require.EqualError(t, err, `unsupported proxy scheme: "ftp"`)
+
+	_, err = httpclient.New(httpclient.Options{ProxyURL: "socks5://127.0.0.1:1080"})
+	require.NoError(t, err)
+}
+
+func TestNew_Timeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		opts httpclient.Options
+	}{
+		{
+			name: "times out waiting for response headers",
+			opts: httpclient.Options{ResponseHeaderTimeout: 20 * time.Millisecond},
+		},
+		{
+			name: "times out the whole request",
+			opts: httpclient.Options{Timeout: 20 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := httpclient.New(tt.opts)
+			require.NoError(t, err)
+
+			start := time.Now()
+			_, err = client.Get(server.URL)
+			require.Error(t, err)
+			require.Less(t, time.Since(start), 150*time.Millisecond)
+		})
+	}
+}
+
+func TestNew_URLPolicy(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	blocking, err := urlpolicy.New(urlpolicy.Options{})
+	require.NoError(t, err)
+	allowingLoopback, err := urlpolicy.New(urlpolicy.Options{AllowedCIDRs: []string{"127.0.0.0/8"}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name              string
+		policy            *urlpolicy.Policy
+		url               string
+		expectedErr       string
+		expectedRequested []string
+	}{
+		{
+			name:        "blocks internal addresses before dialing",
+			policy:      blocking,
+			url:         server.URL,
+			expectedErr: "url blocked: address 127.0.0.1 is internal",
+		},
+		{
+			name:        "blocks hostnames resolving to internal addresses when dialing",
+			policy:      blocking,
+			url:         "http://localhost:" + port,
+			expectedErr: "is internal",
+		},
+		{
+			name:              "blocks redirects to internal addresses",
+			policy:            allowingLoopback,
+			url:               server.URL + "/redirect",
+			expectedErr:       "url blocked: address 169.254.169.254 is internal",
+			expectedRequested: []string{"/redirect"},
+		},
+		{
+			name:              "allows addresses of allowed cidrs",
+			policy:            allowingLoopback,
+			url:               server.URL + "/page",
+			expectedRequested: []string{"/page"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested = nil
+			client, err := httpclient.New(httpclient.Options{URLPolicy: tt.policy})
+			require.NoError(t, err)
+
+			res, err := client.Get(tt.url)
+			if tt.expectedErr != "" {
+				require.ErrorIs(t, err, urlpolicy.ErrBlocked)
+				require.ErrorContains(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				res.Body.Close()
+			}
+
+			require.Equal(t, tt.expectedRequested, requested)
+		})
+	}
+	t.Run("ignores the proxy of the environment, which would hide the target from the URL policy", func(t *testing.T) {
+		var proxied []string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied = append(proxied, r.URL.String())
+		}))
+		defer proxy.Close()
+		// net/http reads these once per process, the test only catches a regression when it is the first to use them
+		t.Setenv("HTTP_PROXY", proxy.URL)
+		t.Setenv("HTTPS_PROXY", proxy.URL)
+
+		// The proxy is reachable, the request would go through it unchecked if it was used
+		client, err := httpclient.New(httpclient.Options{URLPolicy: allowingLoopback})
+		require.NoError(t, err)
+
+		// The hostname is dialed directly, where its address is checked, and doesn't resolve here
+		_, err = client.Get("http://internal.example/admin")
+		require.Error(t, err)
+		require.Empty(t, proxied)
+	})
+}
@@ -0,0 +1,139 @@
+package linkchecker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// maxDrainBytes is how much of a GET response body is read so the connection can be reused
+const maxDrainBytes = 4 << 10
+
+type checker struct {
+	httpClient *http.Client
+	timeout    time.Duration
+	slots      chan struct{}
+	logger     zerolog.Logger
+}
+
+// NewLinkChecker returns a checker sending at most concurrentLimit requests at a time, across every caller.
+// Each link is given timeout to answer, redirects are reported instead of followed.
+func NewLinkChecker(httpClient *http.Client, concurrentLimit int, timeout time.Duration) *checker {
+	// Copy the client so redirects are only disabled for link checks
+	client := *httpClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &checker{
+		httpClient: &client,
+		timeout:    timeout,
+		slots:      make(chan struct{}, concurrentLimit),
+		logger:     log.With().Str("package", "linkchecker").Str("client", "LinkChecker").Logger(),
+	}
+}
+
+// Check checks every url and returns the results in the same order
+func (c *checker) Check(ctx context.Context, urls []string) []Result {
+	results := make([]Result, len(urls))
+
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.checkWithinBudget(ctx, url)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (c *checker) checkWithinBudget(ctx context.Context, url string) Result {
+	select {
+	case c.slots <- struct{}{}:
+		defer func() { <-c.slots }()
+	case <-ctx.Done():
+		return Result{URL: url, ErrorClass: ErrorClassCancelled, Error: ctx.Err().Error()}
+	}
+
+	// Servers that don't implement HEAD properly often answer it with an error status
+	result := c.check(ctx, url, http.MethodHead)
+	if result.ErrorClass == "" && result.StatusCode >= 400 {
+		result = c.check(ctx, url, http.MethodGet)
+	}
+
+	c.logger.Info().Str("url", url).Int("status_code", result.StatusCode).Str("error_class", string(result.ErrorClass)).Msg("Checked link")
+	return result
+}
+
+func (c *checker) check(ctx context.Context, url string, method string) Result {
+	result := Result{URL: url, Method: method}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		result.ErrorClass = ErrorClassInvalidURL
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	res, err := c.httpClient.Do(req)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.ErrorClass = classifyError(err)
+		result.Error = err.Error()
+		return result
+	}
+	defer res.Body.Close()
+	_, _ = io.CopyN(io.Discard, res.Body, maxDrainBytes)
+
+	result.StatusCode = res.StatusCode
+	if location, err := res.Location(); err == nil {
+		result.RedirectURL = location.String()
+	}
+
+	return result
+}
+
+func classifyError(err error) ErrorClass {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	var recordHeaderErr tls.RecordHeaderError
+	var certVerificationErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalidErr x509.CertificateInvalidError
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.As(err, &dnsErr):
+		return ErrorClassDNS
+	case errors.As(err, &recordHeaderErr), errors.As(err, &certVerificationErr), errors.As(err, &unknownAuthorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &certInvalidErr):
+		return ErrorClassTLS
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.As(err, &opErr):
+		return ErrorClassConnection
+	default:
+		return ErrorClassOther
+	}
+}
@@ -0,0 +1,154 @@
+package linkchecker_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/linkchecker"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Check(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	closedServer := httptest.NewServer(mux)
+	closedServer.Close()
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedResult linkchecker.Result
+		expectedBroken bool
+	}{
+		{
+			name:           "reports the status of reachable links",
+			url:            server.URL + "/ok",
+			expectedResult: linkchecker.Result{Method: http.MethodHead, StatusCode: http.StatusOK},
+		},
+		{
+			name:           "reports the redirect target without following it",
+			url:            server.URL + "/moved",
+			expectedResult: linkchecker.Result{Method: http.MethodHead, StatusCode: http.StatusMovedPermanently, RedirectURL: server.URL + "/ok"},
+		},
+		{
+			name:           "falls back to GET when HEAD is rejected",
+			url:            server.URL + "/no-head",
+			expectedResult: linkchecker.Result{Method: http.MethodGet, StatusCode: http.StatusOK},
+		},
+		{
+			name:           "reports error statuses as broken",
+			url:            server.URL + "/missing",
+			expectedResult: linkchecker.Result{Method: http.MethodGet, StatusCode: http.StatusNotFound},
+			expectedBroken: true,
+		},
+		{
+			name:           "classifies timeouts",
+			url:            server.URL + "/slow",
+			expectedResult: linkchecker.Result{Method: http.MethodHead, ErrorClass: linkchecker.ErrorClassTimeout},
+			expectedBroken: true,
+		},
+		{
+			name:           "classifies refused connections",
+			url:            closedServer.URL + "/ok",
+			expectedResult: linkchecker.Result{Method: http.MethodHead, ErrorClass: linkchecker.ErrorClassConnection},
+			expectedBroken: true,
+		},
+		{
+			name:           "classifies invalid urls",
+			url:            "http://exa mple.com",
+			expectedResult: linkchecker.Result{Method: http.MethodHead, ErrorClass: linkchecker.ErrorClassInvalidURL},
+			expectedBroken: true,
+		},
+	}
+
+	checker := linkchecker.NewLinkChecker(&http.Client{}, 2, 200*time.Millisecond)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := checker.Check(context.Background(), []string{tt.url})
+			require.Len(t, results, 1)
+
+			result := results[0]
+			require.Equal(t, tt.expectedBroken, result.Broken())
+
+			// Latency and error messages depend on the environment
+			if tt.expectedResult.ErrorClass != "" {
+				require.NotEmpty(t, result.Error)
+			}
+			result.Latency = 0
+			result.Error = ""
+			tt.expectedResult.URL = tt.url
+
+			require.Equal(t, tt.expectedResult, result)
+		})
+	}
+}
+
+func TestChecker_CheckStaysWithinConcurrencyBudget(t *testing.T) {
+	var inflight, maxInflight atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inflight.Add(1)
+		defer inflight.Add(-1)
+
+		for {
+			seen := maxInflight.Load()
+			if current <= seen || maxInflight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	checker := linkchecker.NewLinkChecker(&http.Client{}, 2, time.Second)
+
+	urls := []string{}
+	for i := 0; i < 10; i++ {
+		urls = append(urls, server.URL+"/"+string(rune('a'+i)))
+	}
+
+	// Concurrent callers share the same budget
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results := checker.Check(context.Background(), urls)
+			require.Len(t, results, len(urls))
+			for j, result := range results {
+				require.Equal(t, urls[j], result.URL)
+				require.Equal(t, http.StatusOK, result.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int64(2), maxInflight.Load())
+}
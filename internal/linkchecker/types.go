@@ -0,0 +1,33 @@
+package linkchecker
+
+import "time"
+
+// ErrorClass tells why a link couldn't be checked
+type ErrorClass string
+
+const (
+	ErrorClassInvalidURL ErrorClass = "invalid_url"
+	ErrorClassDNS        ErrorClass = "dns"
+	ErrorClassConnection ErrorClass = "connection"
+	ErrorClassTLS        ErrorClass = "tls"
+	ErrorClassTimeout    ErrorClass = "timeout"
+	ErrorClassCancelled  ErrorClass = "cancelled"
+	ErrorClassOther      ErrorClass = "other"
+)
+
+type Result struct {
+	URL string
+	// Method is the method of the request the result comes from, GET when HEAD was rejected
+	Method     string
+	StatusCode int
+	// RedirectURL is the resolved Location of a redirect response, redirects aren't followed
+	RedirectURL string
+	Latency     time.Duration
+	ErrorClass  ErrorClass
+	Error       string
+}
+
+// Broken tells whether the link couldn't be reached or answered with an error status
+func (r Result) Broken() bool {
+	return r.ErrorClass != "" || r.StatusCode >= 400
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "domaincrawler"
+
+var (
+	// ExtractorFetches counts the fetches started by the extractor, coalesced callers excluded
+	ExtractorFetches = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "extractor",
+		Name:      "fetches_total",
+		Help:      "Number of fetches started by the extractor.",
+	})
+
+	// ExtractorFetchesCoalesced counts the callers which shared an in-flight fetch of the same URL
+	ExtractorFetchesCoalesced = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "extractor",
+		Name:      "fetches_coalesced_total",
+		Help:      "Number of fetches which shared the result of an in-flight fetch of the same URL instead of starting their own.",
+	})
+
+	// ExtractorFetchRetries counts the fetches retried after a network error, 429 or 5xx response
+	ExtractorFetchRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "extractor",
+		Name:      "fetch_retries_total",
+		Help:      "Number of fetches retried after a network error, 429 or 5xx response.",
+	})
+
+	// ExtractorBreakerState is the circuit state of the hosts which failed, 1 for half-open and 2 for open, closed circuits aren't reported
+	ExtractorBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "extractor",
+		Name:      "breaker_state",
+		Help:      "Circuit breaker state of the host, 1 when half-open and 2 when open.",
+	}, []string{"host"})
+
+	// ExtractorBreakerOpened counts the circuits opened after failures of their host
+	ExtractorBreakerOpened = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "extractor",
+		Name:      "breaker_opened_total",
+		Help:      "Number of times the circuit of a host opened after consecutive failures or a failed probe.",
+	})
+
+	// ExtractorBreakerRejections counts the fetches failed fast because the circuit of their host was open
+	ExtractorBreakerRejections = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "extractor",
+		Name:      "breaker_rejections_total",
+		Help:      "Number of fetch attempts failed without contacting the host because its circuit was open.",
+	})
+
+	// CrawlHostQueueDepth is the number of pages waiting for a per-host slot, hosts without waiting pages aren't reported
+	CrawlHostQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "crawl",
+		Name:      "host_queue_depth",
+		Help:      "Number of pages waiting for the per-host concurrency limit or request delay of their host.",
+	}, []string{"host"})
+)
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
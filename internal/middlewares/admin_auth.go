@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/jponc/domain-crawler/internal/errs"
+)
+
+// AdminAuth only lets through requests sending token as a bearer token.
+// An empty token rejects every request, so admin endpoints are disabled until a token is configured.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				w.WriteHeader(http.StatusUnauthorized)
+
+				response, _ := json.Marshal(&errs.ErrorResponse{Error: "unauthorized"})
+				_, _ = w.Write(response)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package robots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/urlpolicy"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// maxRobotsBytes is how much of a robots.txt is parsed, see RFC 9309 section 2.5
+const maxRobotsBytes = 500 << 10
+
+// fetchTimeout bounds robots.txt fetches, which don't stop when the caller gives up
+const fetchTimeout = 30 * time.Second
+
+// hostRules are the cached rules of a host, done is closed once they are fetched
+type hostRules struct {
+	done      chan struct{}
+	rules     *Rules
+	fetchedAt time.Time
+}
+
+type checker struct {
+	httpClient *http.Client
+	userAgent  string
+	ttl        time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostRules
+	// sweptAt is when expired hosts were last evicted
+	sweptAt time.Time
+
+	logger zerolog.Logger
+}
+
+// NewRobotsChecker returns a checker evaluating the rules for the userAgent token, robots.txt files are cached per host for ttl
+func NewRobotsChecker(httpClient *http.Client, userAgent string, ttl time.Duration) *checker {
+	return &checker{
+		httpClient: httpClient,
+		userAgent:  userAgent,
+		ttl:        ttl,
+		hosts:      map[string]*hostRules{},
+		logger:     log.With().Str("package", "robots").Str("client", "RobotsChecker").Logger(),
+	}
+}
+
+// Check tells whether the url may be crawled according to the robots.txt of its host
+func (c *checker) Check(ctx context.Context, rawURL string) (Decision, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	rules, err := c.rulesFor(ctx, u.Scheme, u.Host)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	return Decision{Allowed: rules.Allowed(path), CrawlDelay: rules.CrawlDelay()}, nil
+}
+
+// rulesFor returns the cached rules of the host, concurrent callers share a single fetch
+func (c *checker) rulesFor(ctx context.Context, scheme string, host string) (*Rules, error) {
+	key := scheme + "://" + host
+
+	c.mu.Lock()
+	c.evictExpired()
+	entry, exists := c.hosts[key]
+	if exists {
+		select {
+		case <-entry.done:
+			if time.Since(entry.fetchedAt) >= c.ttl {
+				exists = false
+			}
+		default:
+		}
+	}
+
+	if !exists {
+		entry = &hostRules{done: make(chan struct{})}
+		c.hosts[key] = entry
+
+		// The fetch outlives the caller's context since its result is shared
+		go func() {
+			fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+			defer cancel()
+			entry.rules = c.fetch(fetchCtx, key)
+			entry.fetchedAt = time.Now()
+			close(entry.done)
+		}()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-entry.done:
+		return entry.rules, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// evictExpired forgets the rules fetched more than ttl ago so hosts which aren't crawled anymore don't pile up,
+// the map is only swept once per ttl. It must be called with mu held.
+func (c *checker) evictExpired() {
+	now := time.Now()
+	if now.Sub(c.sweptAt) < c.ttl {
+		return
+	}
+	c.sweptAt = now
+
+	for key, entry := range c.hosts {
+		select {
+		case <-entry.done:
+			if now.Sub(entry.fetchedAt) >= c.ttl {
+				delete(c.hosts, key)
+			}
+		default:
+		}
+	}
+}
+
+// fetch downloads and parses the robots.txt of the origin.
+// Sites without one allow everything, sites whose robots.txt is unreachable disallow everything.
+func (c *checker) fetch(ctx context.Context, origin string) *Rules {
+	robotsURL := origin + "/robots.txt"
+	logger := c.logger.With().Str("url", robotsURL).Logger()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to create robots.txt request, disallowing the host")
+		return disallowAll
+	}
+
+	res, err := c.httpClient.Do(req)
+	if errors.Is(err, urlpolicy.ErrBlocked) {
+		// The pages of the host are blocked as well, they are reported as such rather than disallowed
+		logger.Warn().Err(err).Msg("robots.txt is blocked by the URL policy, allowing the host")
+		return allowAll
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to fetch robots.txt, disallowing the host")
+		return disallowAll
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 500:
+		logger.Error().Int("status_code", res.StatusCode).Msg("robots.txt is unavailable, disallowing the host")
+		return disallowAll
+	case res.StatusCode >= 400:
+		logger.Info().Int("status_code", res.StatusCode).Msg("No robots.txt, allowing the host")
+		return allowAll
+	case res.StatusCode != http.StatusOK:
+		logger.Error().Int("status_code", res.StatusCode).Msg("Unexpected robots.txt status, disallowing the host")
+		return disallowAll
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxRobotsBytes))
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to read robots.txt, disallowing the host")
+		return disallowAll
+	}
+
+	logger.Info().Msg("Fetched robots.txt")
+	return Parse(string(data), c.userAgent)
+}
@@ -0,0 +1,166 @@
+package robots_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/robots"
+	"github.com/jponc/domain-crawler/internal/urlpolicy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Check(t *testing.T) {
+	tests := []struct {
+		name             string
+		statusCode       int
+		body             string
+		path             string
+		expectedDecision robots.Decision
+	}{
+		{
+			name:       "follows the rules of the robots.txt",
+			statusCode: http.StatusOK,
+			body: `
+				User-agent: domaincrawler
+				Disallow: /private
+				Crawl-delay: 2`,
+			path:             "/private?page=1",
+			expectedDecision: robots.Decision{Allowed: false, CrawlDelay: 2 * time.Second},
+		},
+		{
+			name:             "allows everything when there is no robots.txt",
+			statusCode:       http.StatusNotFound,
+			path:             "/private",
+			expectedDecision: robots.Decision{Allowed: true},
+		},
+		{
+			name:             "disallows everything when the robots.txt is unavailable",
+			statusCode:       http.StatusServiceUnavailable,
+			path:             "/",
+			expectedDecision: robots.Decision{Allowed: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/robots.txt", r.URL.Path)
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			checker := robots.NewRobotsChecker(server.Client(), "domaincrawler", time.Hour)
+
+			decision, err := checker.Check(context.Background(), server.URL+tt.path)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedDecision, decision)
+		})
+	}
+}
+
+func TestChecker_CheckDisallowsUnreachableHosts(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	checker := robots.NewRobotsChecker(server.Client(), "domaincrawler", time.Hour)
+
+	decision, err := checker.Check(context.Background(), server.URL+"/")
+	require.NoError(t, err)
+	require.False(t, decision.Allowed)
+}
+
+func TestChecker_CheckAllowsBlockedHosts(t *testing.T) {
+	httpClient := &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return nil, fmt.Errorf("%w: address 10.0.0.1 is internal", urlpolicy.ErrBlocked)
+		}),
+	}
+	checker := robots.NewRobotsChecker(httpClient, "domaincrawler", time.Hour)
+
+	// The pages are blocked as well, they are reported as such by the extractor
+	decision, err := checker.Check(context.Background(), "http://internal.example.com/")
+	require.NoError(t, err)
+	require.True(t, decision.Allowed)
+}
+
+func TestChecker_CheckCachesRobotsPerHost(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		// Give concurrent callers time to pile up on the same fetch
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte("User-agent: *\nDisallow: /private"))
+	}))
+	defer server.Close()
+
+	checker := robots.NewRobotsChecker(server.Client(), "domaincrawler", time.Hour)
+
+	var wg sync.WaitGroup
+	for _, path := range []string{"/a", "/b", "/private", "/c"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decision, err := checker.Check(context.Background(), server.URL+path)
+			require.NoError(t, err)
+			require.Equal(t, path != "/private", decision.Allowed)
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(1), fetches.Load())
+}
+
+func TestChecker_CheckFetchesRobotsAgainOnceExpired(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+	}))
+	defer server.Close()
+
+	checker := robots.NewRobotsChecker(server.Client(), "domaincrawler", 20*time.Millisecond)
+
+	_, err := checker.Check(context.Background(), server.URL+"/")
+	require.NoError(t, err)
+	_, err = checker.Check(context.Background(), server.URL+"/")
+	require.NoError(t, err)
+	require.Equal(t, int32(1), fetches.Load())
+
+	time.Sleep(30 * time.Millisecond)
+
+	_, err = checker.Check(context.Background(), server.URL+"/")
+	require.NoError(t, err)
+	require.Equal(t, int32(2), fetches.Load())
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestChecker_CheckReturnsWhenContextIsDone(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	checker := robots.NewRobotsChecker(server.Client(), "domaincrawler", time.Hour)
+
+	// The first caller of a host doesn't wait for the shared fetch past its own deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := checker.Check(ctx, server.URL+"/")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+}
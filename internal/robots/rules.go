@@ -0,0 +1,146 @@
+package robots
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rule is an Allow or Disallow line of a group
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+// Rules are the robots.txt rules applying to a single user-agent
+type Rules struct {
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+// allowAll is used when the site has no robots.txt
+var allowAll = &Rules{}
+
+// disallowAll is used when the site's robots.txt can't be fetched, see RFC 9309 section 2.3.1.4
+var disallowAll = &Rules{rules: []rule{{allow: false, pattern: "/"}}}
+
+// Parse returns the rules of the groups matching the user-agent token, or of the * group when none does.
+// Groups matching the same user-agent are merged.
+func Parse(body string, userAgent string) *Rules {
+	userAgent = strings.ToLower(userAgent)
+
+	matched := &Rules{}
+	wildcard := &Rules{}
+	hasMatched := false
+
+	// The groups the current lines belong to, a group starts with one or more user-agent lines
+	var current []*Rules
+	inUserAgents := false
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			if !inUserAgents {
+				current = nil
+			}
+			inUserAgents = true
+
+			switch agent := strings.ToLower(value); {
+			case agent == "*":
+				current = append(current, wildcard)
+			case agent == userAgent:
+				current = append(current, matched)
+				hasMatched = true
+			}
+			continue
+		}
+		inUserAgents = false
+
+		for _, group := range current {
+			switch key {
+			case "allow", "disallow":
+				// An empty disallow allows everything, which is the default
+				if value != "" {
+					group.rules = append(group.rules, rule{allow: key == "allow", pattern: value})
+				}
+			case "crawl-delay":
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					group.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	if hasMatched {
+		return matched
+	}
+
+	return wildcard
+}
+
+// Allowed tells whether the path, including its query, may be crawled.
+// The longest matching rule wins, Allow wins over Disallow when they are as long.
+func (r *Rules) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+
+	allowed := true
+	longest := -1
+	for _, rule := range r.rules {
+		if !matchPattern(rule.pattern, path) {
+			continue
+		}
+
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			longest = len(rule.pattern)
+			allowed = rule.allow
+		}
+	}
+
+	return allowed
+}
+
+// CrawlDelay is the delay asked between requests to the host, zero when not set
+func (r *Rules) CrawlDelay() time.Duration {
+	return r.crawlDelay
+}
+
+// matchPattern matches a rule path against the path, * matches any sequence of characters and
+// a trailing $ anchors the pattern to the end of the path
+func matchPattern(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+
+	// The first part must be a prefix of the path
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+
+	for i, part := range parts[1:] {
+		// The last part of an anchored pattern must end the path
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+
+	return !anchored || rest == ""
+}
@@ -0,0 +1,176 @@
+package robots_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jponc/domain-crawler/internal/robots"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules_Allowed(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		userAgent       string
+		path            string
+		expectedAllowed bool
+	}{
+		{
+			name:            "allows everything without rules",
+			body:            "",
+			userAgent:       "domaincrawler",
+			path:            "/private",
+			expectedAllowed: true,
+		},
+		{
+			name: "disallows paths matching a disallow rule",
+			body: `
+				User-agent: *
+				Disallow: /private`,
+			userAgent:       "domaincrawler",
+			path:            "/private/page",
+			expectedAllowed: false,
+		},
+		{
+			name: "allows paths not matching any rule",
+			body: `
+				User-agent: *
+				Disallow: /private`,
+			userAgent:       "domaincrawler",
+			path:            "/public",
+			expectedAllowed: true,
+		},
+		{
+			name: "the longest matching rule wins",
+			body: `
+				User-agent: *
+				Disallow: /private
+				Allow: /private/open`,
+			userAgent:       "domaincrawler",
+			path:            "/private/open/page",
+			expectedAllowed: true,
+		},
+		{
+			name: "allow wins over an equally long disallow",
+			body: `
+				User-agent: *
+				Disallow: /page
+				Allow: /page`,
+			userAgent:       "domaincrawler",
+			path:            "/page",
+			expectedAllowed: true,
+		},
+		{
+			name: "an empty disallow allows everything",
+			body: `
+				User-agent: *
+				Disallow:`,
+			userAgent:       "domaincrawler",
+			path:            "/page",
+			expectedAllowed: true,
+		},
+		{
+			name: "uses the group of the user agent over the wildcard group",
+			body: `
+				User-agent: *
+				Disallow: /
+
+				User-agent: DomainCrawler
+				Disallow: /private`,
+			userAgent:       "domaincrawler",
+			path:            "/public",
+			expectedAllowed: true,
+		},
+		{
+			name: "merges groups of the same user agent",
+			body: `
+				User-agent: domaincrawler
+				Disallow: /a
+
+				User-agent: domaincrawler
+				Disallow: /b`,
+			userAgent:       "domaincrawler",
+			path:            "/b",
+			expectedAllowed: false,
+		},
+		{
+			name: "applies rules to every user agent of a group",
+			body: `
+				User-agent: otherbot
+				User-agent: domaincrawler
+				Disallow: /private`,
+			userAgent:       "domaincrawler",
+			path:            "/private",
+			expectedAllowed: false,
+		},
+		{
+			name: "ignores groups of other user agents",
+			body: `
+				User-agent: otherbot
+				Disallow: /`,
+			userAgent:       "domaincrawler",
+			path:            "/page",
+			expectedAllowed: true,
+		},
+		{
+			name: "matches wildcards",
+			body: `
+				User-agent: *
+				Disallow: /*.pdf`,
+			userAgent:       "domaincrawler",
+			path:            "/docs/file.pdf?download=1",
+			expectedAllowed: false,
+		},
+		{
+			name: "anchors patterns ending with $",
+			body: `
+				User-agent: *
+				Disallow: /*.pdf$`,
+			userAgent:       "domaincrawler",
+			path:            "/docs/file.pdf?download=1",
+			expectedAllowed: true,
+		},
+		{
+			name: "matches the query string",
+			body: `
+				User-agent: *
+				Disallow: /search?q=`,
+			userAgent:       "domaincrawler",
+			path:            "/search?q=shoes",
+			expectedAllowed: false,
+		},
+		{
+			name: "ignores comments",
+			body: `
+				# Keep out
+				User-agent: * # everyone
+				Disallow: /private # for real`,
+			userAgent:       "domaincrawler",
+			path:            "/private",
+			expectedAllowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := robots.Parse(tt.body, tt.userAgent)
+			require.Equal(t, tt.expectedAllowed, rules.Allowed(tt.path))
+		})
+	}
+}
+
+func TestRules_CrawlDelay(t *testing.T) {
+	rules := robots.Parse(`
+		User-agent: *
+		Crawl-delay: 10
+
+		User-agent: domaincrawler
+		Crawl-delay: 0.5`, "domaincrawler")
+	require.Equal(t, 500*time.Millisecond, rules.CrawlDelay())
+
+	rules = robots.Parse(`
+		User-agent: *
+		Crawl-delay: soon`, "domaincrawler")
+	require.Equal(t, time.Duration(0), rules.CrawlDelay())
+}
@@ -0,0 +1,10 @@
+package robots
+
+import "time"
+
+// Decision is the outcome of checking a URL against the robots.txt of its host
+type Decision struct {
+	Allowed bool
+	// CrawlDelay is the delay the host asks between requests, zero when not set
+	CrawlDelay time.Duration
+}
@@ -0,0 +1,207 @@
+package urlpolicy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// ErrBlocked is returned for URLs the service must not fetch
+var ErrBlocked = errors.New("url blocked")
+
+// blockedPrefixes are the ranges which aren't reachable from the internet, on top of those recognised by netip.Addr
+var blockedPrefixes = []netip.Prefix{
+	// "This network", e.g. 0.0.0.0 reaches the local host on Linux
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// Carrier-grade NAT, it also holds the metadata endpoint of some cloud providers such as 100.100.100.200
+	netip.MustParsePrefix("100.64.0.0/10"),
+	// IETF protocol assignments
+	netip.MustParsePrefix("192.0.0.0/24"),
+	// Benchmarking
+	netip.MustParsePrefix("198.18.0.0/15"),
+	// Reserved, broadcast included
+	netip.MustParsePrefix("240.0.0.0/4"),
+	// NAT64 and 6to4 embed IPv4 addresses which could be private
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// Options configures the hosts and networks reachable on top of the default rules
+type Options struct {
+	// AllowedHosts restricts fetches to these hosts when set
+	AllowedHosts []string
+	// DeniedHosts are never fetched
+	DeniedHosts []string
+	// AllowedCIDRs are reachable even though they are private, e.g. a staging network
+	AllowedCIDRs []string
+	// DeniedCIDRs are unreachable on top of the private, loopback, link-local and metadata ranges
+	DeniedCIDRs []string
+}
+
+// Policy tells which URLs the service may fetch, it must be enforced both before fetching and when dialing
+// so hostnames resolving to internal addresses, e.g. after a redirect or through DNS rebinding, are blocked.
+type Policy struct {
+	allowedHosts []string
+	deniedHosts  []string
+	allowedCIDRs []netip.Prefix
+	deniedCIDRs  []netip.Prefix
+}
+
+// New returns the policy configured by opts.
+// Hosts match themselves and their subdomains, e.g. example.com matches www.example.com.
+func New(opts Options) (*Policy, error) {
+	p := &Policy{
+		allowedHosts: normalizeHosts(opts.AllowedHosts),
+		deniedHosts:  normalizeHosts(opts.DeniedHosts),
+	}
+
+	var err error
+	if p.allowedCIDRs, err = parsePrefixes(opts.AllowedCIDRs); err != nil {
+		return nil, fmt.Errorf("failed to parse allowed cidrs: %w", err)
+	}
+	if p.deniedCIDRs, err = parsePrefixes(opts.DeniedCIDRs); err != nil {
+		return nil, fmt.Errorf("failed to parse denied cidrs: %w", err)
+	}
+
+	return p, nil
+}
+
+// CheckURL rejects URLs which aren't http(s), whose host isn't allowed, or whose host is a blocked IP address.
+// Hostnames are only resolved when dialing, see Control.
+func (p *Policy) CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrBlocked, u.Scheme)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrBlocked)
+	}
+
+	if matchesHost(p.deniedHosts, host) {
+		return fmt.Errorf("%w: host %s is denied", ErrBlocked, host)
+	}
+
+	if len(p.allowedHosts) > 0 && !matchesHost(p.allowedHosts, host) {
+		return fmt.Errorf("%w: host %s isn't allowed", ErrBlocked, host)
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.CheckIP(addr)
+	}
+
+	return nil
+}
+
+// CheckIP rejects addresses which aren't reachable from the internet unless they are explicitly allowed
+func (p *Policy) CheckIP(addr netip.Addr) error {
+	addr = addr.Unmap()
+
+	for _, prefix := range p.deniedCIDRs {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: address %s is denied", ErrBlocked, addr)
+		}
+	}
+
+	for _, prefix := range p.allowedCIDRs {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+
+	if isInternal(addr) {
+		return fmt.Errorf("%w: address %s is internal", ErrBlocked, addr)
+	}
+
+	return nil
+}
+
+// Control checks the address being dialed, it is meant for net.Dialer.Control
+func (p *Policy) Control(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse dialed address: %w", err)
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("failed to parse dialed address: %w", err)
+	}
+
+	return p.CheckIP(addr)
+}
+
+// isInternal tells whether the address is private, loopback, link-local (cloud metadata endpoints included) or reserved
+func isInternal(addr netip.Addr) bool {
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() ||
+		addr.IsMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsLinkLocalMulticast() {
+		return true
+	}
+
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeHosts(hosts []string) []string {
+	normalized := []string{}
+	for _, h := range hosts {
+		h = strings.ToLower(strings.Trim(strings.TrimSpace(h), "."))
+		if h != "" {
+			normalized = append(normalized, h)
+		}
+	}
+
+	return normalized
+}
+
+// parsePrefixes parses CIDRs, bare addresses are read as a single address prefix
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := []netip.Prefix{}
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
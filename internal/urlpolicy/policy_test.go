@@ -0,0 +1,123 @@
+package urlpolicy_test
+
+import (
+	"testing"
+
+	"github.com/jponc/domain-crawler/internal/urlpolicy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_CheckURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        urlpolicy.Options
+		url         string
+		expectedErr string
+	}{
+		{
+			name: "allows public urls",
+			url:  "https://example.com/page",
+		},
+		{
+			name: "allows public addresses",
+			url:  "http://93.184.215.14/",
+		},
+		{
+			name:        "rejects other schemes",
+			url:         "file:///etc/passwd",
+			expectedErr: `url blocked: unsupported scheme "file"`,
+		},
+		{
+			name:        "rejects urls without a host",
+			url:         "http:///page",
+			expectedErr: "url blocked: missing host",
+		},
+		{
+			name:        "rejects the metadata endpoint",
+			url:         "http://169.254.169.254/latest/meta-data/",
+			expectedErr: "url blocked: address 169.254.169.254 is internal",
+		},
+		{
+			name:        "rejects loopback addresses",
+			url:         "http://127.0.0.1:8080/admin",
+			expectedErr: "url blocked: address 127.0.0.1 is internal",
+		},
+		{
+			name:        "rejects private addresses",
+			url:         "http://10.1.2.3/",
+			expectedErr: "url blocked: address 10.1.2.3 is internal",
+		},
+		{
+			name:        "rejects IPv6 loopback and unique local addresses",
+			url:         "http://[fd00:ec2::254]/",
+			expectedErr: "url blocked: address fd00:ec2::254 is internal",
+		},
+		{
+			name:        "rejects IPv4-mapped IPv6 addresses of internal addresses",
+			url:         "http://[::ffff:127.0.0.1]/",
+			expectedErr: "url blocked: address 127.0.0.1 is internal",
+		},
+		{
+			name:        "rejects the unspecified address",
+			url:         "http://0.0.0.0/",
+			expectedErr: "url blocked: address 0.0.0.0 is internal",
+		},
+		{
+			name:        "rejects carrier-grade NAT addresses",
+			url:         "http://100.100.100.200/",
+			expectedErr: "url blocked: address 100.100.100.200 is internal",
+		},
+		{
+			name: "allows internal addresses of allowed cidrs",
+			opts: urlpolicy.Options{AllowedCIDRs: []string{"10.0.0.0/8"}},
+			url:  "http://10.1.2.3/",
+		},
+		{
+			name:        "rejects denied cidrs even when allowed",
+			opts:        urlpolicy.Options{AllowedCIDRs: []string{"10.0.0.0/8"}, DeniedCIDRs: []string{"10.1.2.3"}},
+			url:         "http://10.1.2.3/",
+			expectedErr: "url blocked: address 10.1.2.3 is denied",
+		},
+		{
+			name:        "rejects denied hosts and their subdomains",
+			opts:        urlpolicy.Options{DeniedHosts: []string{"internal.example.com"}},
+			url:         "http://Admin.Internal.Example.com./",
+			expectedErr: "url blocked: host admin.internal.example.com is denied",
+		},
+		{
+			name: "allows allowed hosts and their subdomains",
+			opts: urlpolicy.Options{AllowedHosts: []string{"example.com"}},
+			url:  "http://www.example.com/",
+		},
+		{
+			name:        "rejects hosts which aren't allowed",
+			opts:        urlpolicy.Options{AllowedHosts: []string{"example.com"}},
+			url:         "http://notexample.com/",
+			expectedErr: "url blocked: host notexample.com isn't allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := urlpolicy.New(tt.opts)
+			require.NoError(t, err)
+
+			err = p.CheckURL(tt.url)
+			if tt.expectedErr != "" {
+				require.ErrorIs(t, err, urlpolicy.ErrBlocked)
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNew_InvalidCIDRs(t *testing.T) {
+	_, err := urlpolicy.New(urlpolicy.Options{DeniedCIDRs: []string{"10.0.0.0/33"}})
+	require.Error(t, err)
+
+	_, err = urlpolicy.New(urlpolicy.Options{AllowedCIDRs: []string{"not an address"}})
+	require.Error(t, err)
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// ResolveURL resolves href against base and returns an absolute http(s) URL without its fragment.
+// It returns false when href can't be parsed or doesn't point to an http(s) resource.
+func ResolveURL(base string, href string) (string, bool) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", false
+	}
+
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", false
+	}
+
+	resolved := baseURL.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return "", false
+	}
+
+	if resolved.Host == "" {
+		return "", false
+	}
+
+	resolved.Fragment = ""
+	resolved.RawFragment = ""
+
+	return resolved.String(), true
+}
+
+// RegistrableDomain returns the registrable domain (eTLD+1) of the given URL, e.g. "example.co.uk"
+// for "https://blog.example.co.uk/post". IP addresses and single label hosts are returned as is.
+func RegistrableDomain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if net.ParseIP(host) != nil {
+		return host
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+
+	return domain
+}
+
+// NormalizeURL returns a canonical form of the URL so equivalent URLs compare equal:
+// lowercase scheme and host, no default port, no fragment and "/" for an empty path.
+// URLs which can't be parsed are returned as is.
+func NormalizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = u.Hostname()
+		// IPv6 addresses keep their brackets, which separate them from the port
+		if strings.Contains(u.Host, ":") {
+			u.Host = "[" + u.Host + "]"
+		}
+	}
+
+	if u.Host != "" && u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u.String()
+}
+
+// trackingParams are query parameters only used for analytics, they don't change the resource
+var trackingParams = map[string]bool{
+	"gclid":   true,
+	"dclid":   true,
+	"fbclid":  true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+	"_gl":     true,
+}
+
+// StripTrackingParams removes analytics query parameters such as utm_source or gclid from the URL,
+// the remaining parameters are sorted. URLs which can't be parsed are returned as is.
+func StripTrackingParams(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+
+	query := u.Query()
+	for name := range query {
+		lowerName := strings.ToLower(name)
+		if strings.HasPrefix(lowerName, "utm_") || trackingParams[lowerName] {
+			query.Del(name)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,180 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/jponc/domain-crawler/internal/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		base       string
+		href       string
+		expected   string
+		expectedOk bool
+	}{
+		{
+			name:       "resolves relative path",
+			base:       "https://example.com/blog/post",
+			href:       "other",
+			expected:   "https://example.com/blog/other",
+			expectedOk: true,
+		},
+		{
+			name:       "resolves root relative path",
+			base:       "https://example.com/blog/post",
+			href:       "/about",
+			expected:   "https://example.com/about",
+			expectedOk: true,
+		},
+		{
+			name:       "keeps absolute url and strips fragment",
+			base:       "https://example.com",
+			href:       "https://other.com/page#section",
+			expected:   "https://other.com/page",
+			expectedOk: true,
+		},
+		{
+			name:       "rejects mailto links",
+			base:       "https://example.com",
+			href:       "mailto:hello@example.com",
+			expectedOk: false,
+		},
+		{
+			name:       "rejects javascript links",
+			base:       "https://example.com",
+			href:       "javascript:void(0)",
+			expectedOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := utils.ResolveURL(tt.base, tt.href)
+			require.Equal(t, tt.expectedOk, ok)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{
+			name:     "returns domain for apex url",
+			url:      "https://example.com/page",
+			expected: "example.com",
+		},
+		{
+			name:     "returns domain for subdomain url",
+			url:      "https://Blog.Example.com/page",
+			expected: "example.com",
+		},
+		{
+			name:     "handles multi label public suffixes",
+			url:      "https://www.example.co.uk",
+			expected: "example.co.uk",
+		},
+		{
+			name:     "returns ip addresses as is",
+			url:      "http://127.0.0.1:8080",
+			expected: "127.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, utils.RegistrableDomain(tt.url))
+		})
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{
+			name:     "lowercases scheme and host",
+			url:      "HTTPS://Example.COM/Path",
+			expected: "https://example.com/Path",
+		},
+		{
+			name:     "removes default ports",
+			url:      "http://example.com:80/a",
+			expected: "http://example.com/a",
+		},
+		{
+			name:     "keeps non default ports",
+			url:      "https://example.com:8443/a",
+			expected: "https://example.com:8443/a",
+		},
+		{
+			name:     "removes default ports of IPv6 hosts",
+			url:      "http://[::1]:80/a",
+			expected: "http://[::1]/a",
+		},
+		{
+			name:     "removes default https ports of IPv6 hosts",
+			url:      "https://[2001:DB8::1]:443/",
+			expected: "https://[2001:db8::1]/",
+		},
+		{
+			name:     "keeps non default ports of IPv6 hosts",
+			url:      "http://[::1]:8080/a",
+			expected: "http://[::1]:8080/a",
+		},
+		{
+			name:     "keeps IPv6 hosts without port",
+			url:      "http://[::1]/a",
+			expected: "http://[::1]/a",
+		},
+		{
+			name:     "strips fragment and adds root path",
+			url:      "https://example.com#top",
+			expected: "https://example.com/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, utils.NormalizeURL(tt.url))
+		})
+	}
+}
+
+func TestStripTrackingParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{
+			name:     "removes utm and click id params",
+			url:      "https://example.com/a?utm_source=news&UTM_Medium=email&gclid=1&fbclid=2",
+			expected: "https://example.com/a",
+		},
+		{
+			name:     "keeps other params",
+			url:      "https://example.com/a?page=2&utm_campaign=x&q=go",
+			expected: "https://example.com/a?page=2&q=go",
+		},
+		{
+			name:     "leaves urls without query as is",
+			url:      "https://example.com/a",
+			expected: "https://example.com/a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, utils.StripTrackingParams(tt.url))
+		})
+	}
+}